@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// clientIdleTimeout is how long a client is kept after it was last used, before it is logged out and dropped from the cache
+const clientIdleTimeout = 15 * time.Minute
+
+var (
+	requestRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nitro_request_retries_total",
+			Help: "Number of Nitro API requests and logins retried after a transient failure.",
+		},
+		[]string{
+			"ns_instance",
+		},
+	)
+
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nitro_requests_total",
+			Help: "Number of requests sent to the Nitro API, by endpoint and HTTP status; the code is error if no response was received.",
+		},
+		[]string{
+			"ns_instance",
+			"endpoint",
+			"code",
+		},
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nitro_request_duration_seconds",
+			Help:    "Time taken by requests to the Nitro API, until the response had been read.",
+			Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{
+			"ns_instance",
+			"endpoint",
+		},
+	)
+
+	responseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nitro_response_size_bytes",
+			Help:    "Size of the bodies of Nitro API responses.",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+		},
+		[]string{
+			"ns_instance",
+			"endpoint",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestRetries, requestsTotal, requestDuration, responseSize)
+}
+
+// observeRequest records a request sent to a NetScaler in the request metrics, and traces it in the debug log
+func observeRequest(nsInstance string, info netscaler.RequestInfo) {
+	// Requests proxied by an ADM are recorded against the instance they were proxied to
+	if info.Instance != "" {
+		nsInstance = info.Instance
+	}
+
+	code := "error"
+	if info.StatusCode != 0 {
+		code = strconv.Itoa(info.StatusCode)
+	}
+
+	requestsTotal.WithLabelValues(nsInstance, info.Endpoint, code).Inc()
+	requestDuration.WithLabelValues(nsInstance, info.Endpoint).Observe(info.Duration.Seconds())
+
+	if info.StatusCode != 0 {
+		responseSize.WithLabelValues(nsInstance, info.Endpoint).Observe(float64(info.Bytes))
+	}
+
+	keyvals := []interface{}{"msg", "Nitro API request", "ns_instance", nsInstance, "method", info.Method, "endpoint", info.Endpoint, "path", info.Path, "code", code, "duration", info.Duration, "bytes", info.Bytes}
+	if info.Err != nil {
+		keyvals = append(keyvals, "err", info.Err)
+	}
+
+	level.Debug(logger).Log(keyvals...)
+}
+
+// clientCache holds a Nitro client per target and module, so that sessions and connections are kept open across scrapes
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]*netscaler.NitroClient
+	used    map[string]time.Time
+}
+
+var clients = &clientCache{
+	clients: map[string]*netscaler.NitroClient{},
+	used:    map[string]time.Time{},
+}
+
+// get returns the client for the target, creating it on the first scrape of the target with its module, and sharing it between targets behind the same ADM.
+func (cc *clientCache) get(t target) (*netscaler.NitroClient, error) {
+	connURL := t.URL
+	if t.ADM != "" {
+		connURL = t.ADM
+	}
+
+	key := t.Module + "|" + connURL
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.expire(key)
+	cc.used[key] = time.Now()
+
+	if c, ok := cc.clients[key]; ok {
+		return clientForTarget(c, t), nil
+	}
+
+	m := cfg.Modules[t.Module]
+	nsInstance := instanceName(connURL)
+
+	tlsClientConfig, err := t.tlsSettings().tlsClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []netscaler.ClientOption{
+		netscaler.WithTLSConfig(tlsClientConfig),
+		netscaler.WithMaxConcurrentRequests(*m.MaxConcurrentRequests),
+		netscaler.WithPageSize(*m.PageSize),
+		netscaler.WithMaxResponseSize(*m.MaxResponseSize),
+		netscaler.WithRetries(*m.MaxRetries, *m.RetryBackoff),
+		netscaler.WithAuthBackoff(*authBackoff, *authBackoffMax),
+		netscaler.WithRetryHook(func(err error) {
+			level.Warn(logger).Log("msg", "Retrying Nitro API request", "err", err, "ns_instance", nsInstance)
+			requestRetries.WithLabelValues(nsInstance).Inc()
+		}),
+		netscaler.WithRequestHook(func(info netscaler.RequestInfo) {
+			observeRequest(nsInstance, info)
+		}),
+	}
+
+	if t.authMode() == authModeHeader {
+		opts = append(opts, netscaler.WithHeaderAuth())
+	}
+
+	// The credentials are read when they are first needed, and again if the NetScaler rejects them
+	opts = append(opts, netscaler.WithCredentials(func(ctx context.Context) (string, string, error) {
+		return cfg.credentials(ctx, m)
+	}))
+
+	c, err := netscaler.NewNitroClient(connURL, "", "", opts...)
+	if err != nil {
+		delete(cc.used, key)
+		return nil, err
+	}
+
+	cc.clients[key] = c
+
+	return clientForTarget(c, t), nil
+}
+
+// clientForTarget returns the client for a target from the client of the NetScaler or ADM it connects to
+func clientForTarget(c *netscaler.NitroClient, t target) *netscaler.NitroClient {
+	if t.ADM == "" {
+		return c
+	}
+
+	return c.Instance(t.instanceIP())
+}
+
+// expire logs out of and drops every client, other than the one with the given key, which has been idle for clientIdleTimeout; the caller must hold mu.
+func (cc *clientCache) expire(keep string) {
+	for key, used := range cc.used {
+		if key == keep || time.Since(used) < clientIdleTimeout {
+			continue
+		}
+
+		c := cc.clients[key]
+		delete(cc.clients, key)
+		delete(cc.used, key)
+
+		if c == nil {
+			continue
+		}
+
+		level.Debug(logger).Log("msg", "Logging out of idle client", "client", key)
+
+		go func(key string) {
+			err := netscaler.Disconnect(c)
+			if err != nil {
+				level.Error(logger).Log("msg", err, "client", key)
+			}
+		}(key)
+	}
+}
+
+// logoutAll ends the session of every client
+func (cc *clientCache) logoutAll() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	for key, c := range cc.clients {
+		err := netscaler.Disconnect(c)
+		if err != nil {
+			level.Error(logger).Log("msg", err, "client", key)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	interfacesRxBytesPerSecond = prometheus.NewDesc(
+		"interfaces_received_bytes_per_second",
+		"Number of bytes received per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+
+	interfacesTxBytesPerSecond = prometheus.NewDesc(
+		"interfaces_transmitted_bytes_per_second",
+		"Number of bytes transmitted per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+
+	interfacesRxPacketsPerSecond = prometheus.NewDesc(
+		"interfaces_received_packets_per_second",
+		"Number of packets received per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+
+	interfacesTxPacketsPerSecond = prometheus.NewDesc(
+		"interfaces_transmitted_packets_per_second",
+		"Number of packets transmitted per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+
+	interfacesJumboPacketsRxPerSecond = prometheus.NewDesc(
+		"interfaces_jumbo_packets_received_per_second",
+		"Number of bytes received per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+
+	interfacesJumboPacketsTxPerSecond = prometheus.NewDesc(
+		"interfaces_jumbo_packets_transmitted_per_second",
+		"Number of jumbo packets transmitted per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+
+	interfacesErrorPacketsRxPerSecond = prometheus.NewDesc(
+		"interfaces_error_packets_received_per_second",
+		"Number of error packets received per second by specific interfaces",
+		[]string{
+			"ns_instance",
+			"interface",
+			"alias",
+		},
+		nil,
+	)
+)
+
+// collectInterfaces exports the stats of each interface
+func (e *Exporter) collectInterfaces(ch chan<- prometheus.Metric) error {
+	result, err := e.client.FetchContext(e.ctx, netscaler.InterfaceStatsResource, "", nil)
+	if err != nil {
+		return err
+	}
+
+	for _, iface := range result.([]netscaler.InterfaceStats) {
+		e.number(ch, interfacesRxBytesPerSecond, prometheus.GaugeValue, iface.ReceivedBytesPerSecond, e.nsInstance, iface.ID, iface.Alias)
+		e.number(ch, interfacesTxBytesPerSecond, prometheus.GaugeValue, iface.TransmitBytesPerSecond, e.nsInstance, iface.ID, iface.Alias)
+		e.number(ch, interfacesRxPacketsPerSecond, prometheus.GaugeValue, iface.ReceivedPacketsPerSecond, e.nsInstance, iface.ID, iface.Alias)
+		e.number(ch, interfacesTxPacketsPerSecond, prometheus.GaugeValue, iface.TransmitPacketsPerSecond, e.nsInstance, iface.ID, iface.Alias)
+		e.number(ch, interfacesJumboPacketsRxPerSecond, prometheus.GaugeValue, iface.JumboPacketsReceivedPerSecond, e.nsInstance, iface.ID, iface.Alias)
+		e.number(ch, interfacesJumboPacketsTxPerSecond, prometheus.GaugeValue, iface.JumboPacketsTransmittedPerSecond, e.nsInstance, iface.ID, iface.Alias)
+		e.number(ch, interfacesErrorPacketsRxPerSecond, prometheus.GaugeValue, iface.ErrorPacketsReceivedPerSecond, e.nsInstance, iface.ID, iface.Alias)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var modelID = prometheus.NewDesc(
+	"model_id",
+	"NetScaler model - reflects the bandwidth available; for example VPX 10 would report as 10.",
+	[]string{
+		"ns_instance",
+	},
+	nil,
+)
+
+// collectLicense exports the NetScaler model, from the license
+func (e *Exporter) collectLicense(ch chan<- prometheus.Metric) error {
+	result, err := e.client.FetchContext(e.ctx, netscaler.NSLicenseResource, "", nil)
+	if err != nil {
+		return err
+	}
+
+	nslicense := result.(netscaler.NSLicense)
+
+	e.number(ch, modelID, prometheus.GaugeValue, nslicense.ModelID, e.nsInstance)
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	mgmtCPUUsage = prometheus.NewDesc(
+		"mgmt_cpu_usage",
+		"Current CPU utilisation for management",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	pktCPUUsage = prometheus.NewDesc(
+		"pkt_cpu_usage",
+		"Current CPU utilisation for packet engines, excluding management",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	memUsage = prometheus.NewDesc(
+		"mem_usage",
+		"Current memory utilisation",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	flashPartitionUsage = prometheus.NewDesc(
+		"flash_partition_usage",
+		"Used space in /flash partition of the disk, as a percentage.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	varPartitionUsage = prometheus.NewDesc(
+		"var_partition_usage",
+		"Used space in /var partition of the disk, as a percentage. ",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	rxMbPerSec = prometheus.NewDesc(
+		"received_mb_per_second",
+		"Number of Megabits received by the NetScaler appliance per second",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	txMbPerSec = prometheus.NewDesc(
+		"transmit_mb_per_second",
+		"Number of Megabits transmitted by the NetScaler appliance per second",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	httpRequestsRate = prometheus.NewDesc(
+		"http_requests_rate",
+		"HTTP requests received per second",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	httpResponsesRate = prometheus.NewDesc(
+		"http_responses_rate",
+		"HTTP requests sent per second",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	tcpCurrentClientConnections = prometheus.NewDesc(
+		"tcp_current_client_connections",
+		"Client connections, including connections in the Opening, Established, and Closing state.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	tcpCurrentClientConnectionsEstablished = prometheus.NewDesc(
+		"tcp_current_client_connections_established",
+		"Current client connections in the Established state, which indicates that data transfer can occur between the NetScaler and the client.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	tcpCurrentServerConnections = prometheus.NewDesc(
+		"tcp_current_server_connections",
+		"Server connections, including connections in the Opening, Established, and Closing state.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	tcpCurrentServerConnectionsEstablished = prometheus.NewDesc(
+		"tcp_current_server_connections_established",
+		"Current server connections in the Established state, which indicates that data transfer can occur between the NetScaler and the server.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+)
+
+// collectNS exports the system wide stats of the NetScaler
+func (e *Exporter) collectNS(ch chan<- prometheus.Metric) error {
+	result, err := e.client.FetchContext(e.ctx, netscaler.NSStatsResource, "", nil)
+	if err != nil {
+		return err
+	}
+
+	ns := result.(netscaler.NSStats)
+
+	e.number(ch, mgmtCPUUsage, prometheus.GaugeValue, ns.MgmtCPUUsagePcnt, e.nsInstance)
+	e.number(ch, memUsage, prometheus.GaugeValue, ns.MemUsagePcnt, e.nsInstance)
+	e.number(ch, pktCPUUsage, prometheus.GaugeValue, ns.PktCPUUsagePcnt, e.nsInstance)
+	e.number(ch, flashPartitionUsage, prometheus.GaugeValue, ns.FlashPartitionUsage, e.nsInstance)
+	e.number(ch, varPartitionUsage, prometheus.GaugeValue, ns.VarPartitionUsage, e.nsInstance)
+	e.number(ch, rxMbPerSec, prometheus.GaugeValue, ns.ReceivedMbPerSecond, e.nsInstance)
+	e.number(ch, txMbPerSec, prometheus.GaugeValue, ns.TransmitMbPerSecond, e.nsInstance)
+	e.number(ch, httpRequestsRate, prometheus.GaugeValue, ns.HTTPRequestsRate, e.nsInstance)
+	e.number(ch, httpResponsesRate, prometheus.GaugeValue, ns.HTTPResponsesRate, e.nsInstance)
+	e.number(ch, tcpCurrentClientConnections, prometheus.GaugeValue, ns.TCPCurrentClientConnections, e.nsInstance)
+	e.number(ch, tcpCurrentClientConnectionsEstablished, prometheus.GaugeValue, ns.TCPCurrentClientConnectionsEstablished, e.nsInstance)
+	e.number(ch, tcpCurrentServerConnections, prometheus.GaugeValue, ns.TCPCurrentServerConnections, e.nsInstance)
+	e.number(ch, tcpCurrentServerConnectionsEstablished, prometheus.GaugeValue, ns.TCPCurrentServerConnectionsEstablished, e.nsInstance)
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+var (
+	serviceGroupsState = prometheus.NewDesc(
+		"servicegroup_state",
+		"Current state of the server",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsAvgTTFB = prometheus.NewDesc(
+		"servicegroup_average_time_to_first_byte",
+		"Average TTFB between the NetScaler appliance and the server.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsTotalRequests = prometheus.NewDesc(
+		"servicegroup_total_requests",
+		"Total number of requests received on this service",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsRequestsRate = prometheus.NewDesc(
+		"servicegroup_requests_rate",
+		"Rate (/s) counter for totalrequests",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsTotalResponses = prometheus.NewDesc(
+		"servicegroup_total_responses",
+		"Number of responses received on this service.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsResponsesRate = prometheus.NewDesc(
+		"servicegroup_responses_rate",
+		"Rate (/s) counter for totalresponses",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsTotalRequestBytes = prometheus.NewDesc(
+		"servicegroup_total_request_bytes",
+		"Total number of request bytes received on this service",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsRequestBytesRate = prometheus.NewDesc(
+		"servicegroup_request_bytes_rate",
+		"Rate (/s) counter for totalrequestbytes",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsTotalResponseBytes = prometheus.NewDesc(
+		"servicegroup_total_response_bytes",
+		"Number of response bytes received by this service",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsResponseBytesRate = prometheus.NewDesc(
+		"servicegroup_response_bytes_rate",
+		"Rate (/s) counter for totalresponsebytes",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsCurrentClientConnections = prometheus.NewDesc(
+		"servicegroup_current_client_connections",
+		"Number of current client connections.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsSurgeCount = prometheus.NewDesc(
+		"servicegroup_surge_count",
+		"Number of requests in the surge queue.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsCurrentServerConnections = prometheus.NewDesc(
+		"servicegroup_current_server_connections",
+		"Number of current connections to the actual servers behind the virtual server.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsServerEstablishedConnections = prometheus.NewDesc(
+		"servicegroup_server_established_connections",
+		"Number of server connections in ESTABLISHED state.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsCurrentReusePool = prometheus.NewDesc(
+		"servicegroup_current_reuse_pool",
+		"Number of requests in the idle queue/reuse pool.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+
+	serviceGroupsMaxClients = prometheus.NewDesc(
+		"servicegroup_max_clients",
+		"Maximum open connections allowed on this service.",
+		[]string{
+			"ns_instance",
+			"servicegroup",
+			"member",
+			"port",
+		},
+		nil,
+	)
+)
+
+// memberKey identifies a service group member by the address and port the NetScaler reports its stats against
+type memberKey struct {
+	ip   string
+	port int64
+}
+
+// collectServiceGroups exports the stats of every member of every service group, with one request for the bindings and one per group for the stats.
+func (e *Exporter) collectServiceGroups(ch chan<- prometheus.Metric) error {
+	query := e.module.Filters.nitroQuery("servicegroup", "servicegroupname").BulkBindings()
+
+	bindings, err := e.client.FetchContext(e.ctx, netscaler.ServiceGroupMemberBindingsResource, "", query)
+	if err != nil {
+		return err
+	}
+
+	// Member stats only identify the member by IP address and port, so map those back to the server name it was bound with
+	members := map[string]map[memberKey]string{}
+
+	for _, b := range bindings.([]netscaler.ServiceGroupMemberBindings) {
+		if !e.module.Filters.matches("servicegroup", b.ServiceGroupName) {
+			continue
+		}
+
+		if members[b.ServiceGroupName] == nil {
+			members[b.ServiceGroupName] = map[memberKey]string{}
+		}
+
+		members[b.ServiceGroupName][memberKey{b.IP, b.Port}] = b.ServerName
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures int
+		lastErr  error
+	)
+
+	// The Nitro client limits how many of the requests are in flight at once
+	for sgName, servers := range members {
+		wg.Add(1)
+
+		go func(sgName string, servers map[memberKey]string) {
+			defer wg.Done()
+
+			stats, err := e.client.FetchContext(e.ctx, netscaler.ServiceGroupStatsResource, sgName, netscaler.NewQuery().StatBindings())
+			if err != nil {
+				level.Error(logger).Log("msg", err, "ns_instance", e.nsInstance, "servicegroup", sgName)
+
+				mu.Lock()
+				failures++
+				lastErr = err
+				mu.Unlock()
+
+				return
+			}
+
+			for _, sg := range stats.([]netscaler.ServiceGroups) {
+				for _, member := range sg.Members {
+					servername, ok := servers[memberKey{member.PrimaryIPAddress, member.PrimaryPort}]
+					if !ok {
+						servername = member.PrimaryIPAddress
+					}
+
+					e.collectServiceGroupMember(member, sgName, servername, ch)
+				}
+			}
+		}(sgName, servers)
+	}
+
+	wg.Wait()
+
+	if failures > 0 {
+		return errors.Wrapf(lastErr, "%d service group requests failed, last error", failures)
+	}
+
+	return nil
+}
+
+// collectServiceGroupMember exports the stats of a service group member
+func (e *Exporter) collectServiceGroupMember(member netscaler.ServiceGroupMemberStats, sgName string, servername string, ch chan<- prometheus.Metric) {
+	port := strconv.FormatInt(member.PrimaryPort, 10)
+
+	e.state(ch, serviceGroupsState, member.State, e.nsInstance, sgName, servername, port)
+
+	e.number(ch, serviceGroupsAvgTTFB, prometheus.GaugeValue, member.AvgTimeToFirstByte, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsTotalRequests, prometheus.CounterValue, member.TotalRequests, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsRequestsRate, prometheus.GaugeValue, member.RequestsRate, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsTotalResponses, prometheus.CounterValue, member.TotalResponses, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsResponsesRate, prometheus.GaugeValue, member.ResponsesRate, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsTotalRequestBytes, prometheus.CounterValue, member.TotalRequestBytes, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsRequestBytesRate, prometheus.GaugeValue, member.RequestBytesRate, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsTotalResponseBytes, prometheus.CounterValue, member.TotalResponseBytes, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsResponseBytesRate, prometheus.GaugeValue, member.ResponseBytesRate, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsCurrentClientConnections, prometheus.GaugeValue, member.CurrentClientConnections, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsSurgeCount, prometheus.GaugeValue, member.SurgeCount, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsCurrentServerConnections, prometheus.GaugeValue, member.CurrentServerConnections, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsServerEstablishedConnections, prometheus.GaugeValue, member.ServerEstablishedConnections, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsCurrentReusePool, prometheus.GaugeValue, member.CurrentReusePool, e.nsInstance, sgName, servername, port)
+	e.number(ch, serviceGroupsMaxClients, prometheus.GaugeValue, member.MaxClients, e.nsInstance, sgName, servername, port)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	servicesThroughput = prometheus.NewDesc(
+		"service_throughput",
+		"Number of bytes received or sent by this service (Mbps)",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesThroughputRate = prometheus.NewDesc(
+		"service_throughput_rate",
+		"Rate (/s) counter for throughput",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesAvgTTFB = prometheus.NewDesc(
+		"service_average_time_to_first_byte",
+		"Average TTFB between the NetScaler appliance and the server.TTFB is the time interval between sending the request packet to a service and receiving the first response from the service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesState = prometheus.NewDesc(
+		"service_state",
+		"Current state of the service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesTotalRequests = prometheus.NewDesc(
+		"service_total_requests",
+		"Total number of requests received on this service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesRequestsRate = prometheus.NewDesc(
+		"service_request_rate",
+		"Rate (/s) counter for totalrequests",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesTotalResponses = prometheus.NewDesc(
+		"service_total_responses",
+		"Total number of responses received on this service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesResponsesRate = prometheus.NewDesc(
+		"service_responses_rate",
+		"Rate (/s) counter for totalresponses",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesTotalRequestBytes = prometheus.NewDesc(
+		"service_total_request_bytes",
+		"Total number of request bytes received on this service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesRequestBytesRate = prometheus.NewDesc(
+		"service_request_bytes_rate",
+		"Rate (/s) counter for totalrequestbytes",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesTotalResponseBytes = prometheus.NewDesc(
+		"service_total_response_bytes",
+		"Total number of response bytes received on this service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesResponseBytesRate = prometheus.NewDesc(
+		"service_response_bytes_rate",
+		"Rate (/s) counter for totalresponsebytes",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesCurrentClientConns = prometheus.NewDesc(
+		"service_current_client_connections",
+		"Number of current client connections",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesSurgeCount = prometheus.NewDesc(
+		"service_surge_count",
+		"Number of requests in the surge queue",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesCurrentServerConns = prometheus.NewDesc(
+		"service_current_server_connections",
+		"Number of current connections to the actual servers",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesServerEstablishedConnections = prometheus.NewDesc(
+		"service_server_established_connections",
+		"Number of server connections in ESTABLISHED state",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesCurrentReusePool = prometheus.NewDesc(
+		"service_current_reuse_pool",
+		"Number of requests in the idle queue/reuse pool.",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesMaxClients = prometheus.NewDesc(
+		"service_max_clients",
+		"Maximum open connections allowed on this service",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesCurrentLoad = prometheus.NewDesc(
+		"service_current_load",
+		"Load on the service that is calculated from the bound load based monitor",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesVirtualServerServiceHits = prometheus.NewDesc(
+		"service_virtual_server_service_hits",
+		"Number of times that the service has been provided",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesVirtualServerServiceHitsRate = prometheus.NewDesc(
+		"service_virtual_server_service_hits_rate",
+		"Rate (/s) counter for vsvrservicehits",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+
+	servicesActiveTransactions = prometheus.NewDesc(
+		"service_active_transactions",
+		"Number of active transactions handled by this service. (Including those in the surge queue.) Active Transaction means number of transactions currently served by the server including those waiting in the SurgeQ",
+		[]string{
+			"ns_instance",
+			"service",
+		},
+		nil,
+	)
+)
+
+// collectServices exports the stats of each service as it is read from the response
+func (e *Exporter) collectServices(ch chan<- prometheus.Metric) error {
+	return e.client.StreamContext(e.ctx, netscaler.ServiceStatsResource, "", e.module.Filters.nitroQuery("service", "name"), func(resource interface{}) error {
+		service := resource.(netscaler.ServiceStats)
+
+		if !e.module.Filters.matches("service", service.Name) {
+			return nil
+		}
+
+		e.number(ch, servicesThroughput, prometheus.CounterValue, service.Throughput, e.nsInstance, service.Name)
+		e.number(ch, servicesThroughputRate, prometheus.GaugeValue, service.ThroughputRate, e.nsInstance, service.Name)
+		e.number(ch, servicesAvgTTFB, prometheus.GaugeValue, service.AvgTimeToFirstByte, e.nsInstance, service.Name)
+
+		e.state(ch, servicesState, service.State, e.nsInstance, service.Name)
+
+		e.number(ch, servicesTotalRequests, prometheus.CounterValue, service.TotalRequests, e.nsInstance, service.Name)
+		e.number(ch, servicesRequestsRate, prometheus.GaugeValue, service.RequestsRate, e.nsInstance, service.Name)
+		e.number(ch, servicesTotalResponses, prometheus.CounterValue, service.TotalResponses, e.nsInstance, service.Name)
+		e.number(ch, servicesResponsesRate, prometheus.GaugeValue, service.ResponsesRate, e.nsInstance, service.Name)
+		e.number(ch, servicesTotalRequestBytes, prometheus.CounterValue, service.TotalRequestBytes, e.nsInstance, service.Name)
+		e.number(ch, servicesRequestBytesRate, prometheus.GaugeValue, service.RequestBytesRate, e.nsInstance, service.Name)
+		e.number(ch, servicesTotalResponseBytes, prometheus.CounterValue, service.TotalResponseBytes, e.nsInstance, service.Name)
+		e.number(ch, servicesResponseBytesRate, prometheus.GaugeValue, service.ResponseBytesRate, e.nsInstance, service.Name)
+		e.number(ch, servicesCurrentClientConns, prometheus.GaugeValue, service.CurrentClientConnections, e.nsInstance, service.Name)
+		e.number(ch, servicesSurgeCount, prometheus.GaugeValue, service.SurgeCount, e.nsInstance, service.Name)
+		e.number(ch, servicesCurrentServerConns, prometheus.GaugeValue, service.CurrentServerConnections, e.nsInstance, service.Name)
+		e.number(ch, servicesServerEstablishedConnections, prometheus.GaugeValue, service.ServerEstablishedConnections, e.nsInstance, service.Name)
+		e.number(ch, servicesCurrentReusePool, prometheus.GaugeValue, service.CurrentReusePool, e.nsInstance, service.Name)
+		e.number(ch, servicesMaxClients, prometheus.GaugeValue, service.MaxClients, e.nsInstance, service.Name)
+		e.number(ch, servicesCurrentLoad, prometheus.GaugeValue, service.CurrentLoad, e.nsInstance, service.Name)
+		e.number(ch, servicesVirtualServerServiceHits, prometheus.CounterValue, service.ServiceHits, e.nsInstance, service.Name)
+		e.number(ch, servicesVirtualServerServiceHitsRate, prometheus.GaugeValue, service.ServiceHitsRate, e.nsInstance, service.Name)
+		e.number(ch, servicesActiveTransactions, prometheus.GaugeValue, service.ActiveTransactions, e.nsInstance, service.Name)
+
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	virtualServersWaitingRequests = prometheus.NewDesc(
+		"virtual_servers_waiting_requests",
+		"Number of requests waiting on a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersHealth = prometheus.NewDesc(
+		"virtual_servers_health",
+		"Percentage of UP services bound to a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersInactiveServices = prometheus.NewDesc(
+		"virtual_servers_inactive_services",
+		"Number of inactive services bound to a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersActiveServices = prometheus.NewDesc(
+		"virtual_servers_active_services",
+		"Number of active services bound to a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersTotalHits = prometheus.NewDesc(
+		"virtual_servers_total_hits",
+		"Total virtual server hits",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersHitsRate = prometheus.NewDesc(
+		"virtual_servers_hits_rate",
+		"Number of hits/second to a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersTotalRequests = prometheus.NewDesc(
+		"virtual_servers_total_requests",
+		"Total virtual server requests",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersRequestsRate = prometheus.NewDesc(
+		"virtual_servers_requests_rate",
+		"Number of requests/second to a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersTotalResponses = prometheus.NewDesc(
+		"virtual_servers_total_responses",
+		"Total virtual server responses",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersReponsesRate = prometheus.NewDesc(
+		"virtual_servers_responses_rate",
+		"Number of responses/second from a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersTotalRequestBytes = prometheus.NewDesc(
+		"virtual_servers_total_request_bytes",
+		"Total virtual server request bytes",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersRequestBytesRate = prometheus.NewDesc(
+		"virtual_servers_request_bytes_rate",
+		"Number of request bytes/second to a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersTotalResponseBytes = prometheus.NewDesc(
+		"virtual_servers_total_response_bytes",
+		"Total virtual server response bytes",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersReponseBytesRate = prometheus.NewDesc(
+		"virtual_servers_reponse_bytes_rate",
+		"Number of response bytes/second from a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersCurrentClientConnections = prometheus.NewDesc(
+		"virtual_servers_current_client_connections",
+		"Number of current client connections on a specific virtual server",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+
+	virtualServersCurrentServerConnections = prometheus.NewDesc(
+		"virtual_servers_current_server_connections",
+		"Number of current connections to the actual servers behind the specific virtual server.",
+		[]string{
+			"ns_instance",
+			"virtual_server",
+		},
+		nil,
+	)
+)
+
+// collectVirtualServers exports the stats of each virtual server as it is read from the response
+func (e *Exporter) collectVirtualServers(ch chan<- prometheus.Metric) error {
+	return e.client.StreamContext(e.ctx, netscaler.VirtualServerStatsResource, "", e.module.Filters.nitroQuery("lbvserver", "name"), func(resource interface{}) error {
+		vs := resource.(netscaler.VirtualServerStats)
+
+		if !e.module.Filters.matches("lbvserver", vs.Name) {
+			return nil
+		}
+
+		e.number(ch, virtualServersWaitingRequests, prometheus.GaugeValue, vs.WaitingRequests, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersHealth, prometheus.GaugeValue, vs.Health, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersInactiveServices, prometheus.GaugeValue, vs.InactiveServices, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersActiveServices, prometheus.GaugeValue, vs.ActiveServices, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersTotalHits, prometheus.CounterValue, vs.TotalHits, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersHitsRate, prometheus.GaugeValue, vs.HitsRate, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersTotalRequests, prometheus.CounterValue, vs.TotalRequests, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersRequestsRate, prometheus.GaugeValue, vs.RequestsRate, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersTotalResponses, prometheus.CounterValue, vs.TotalResponses, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersReponsesRate, prometheus.GaugeValue, vs.ResponsesRate, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersTotalRequestBytes, prometheus.CounterValue, vs.TotalRequestBytes, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersRequestBytesRate, prometheus.GaugeValue, vs.RequestBytesRate, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersTotalResponseBytes, prometheus.CounterValue, vs.TotalResponseBytes, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersReponseBytesRate, prometheus.GaugeValue, vs.ResponseBytesRate, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersCurrentClientConnections, prometheus.GaugeValue, vs.CurrentClientConnections, e.nsInstance, vs.Name)
+		e.number(ch, virtualServersCurrentServerConnections, prometheus.GaugeValue, vs.CurrentServerConnections, e.nsInstance, vs.Name)
+
+		return nil
+	})
+}
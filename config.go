@@ -0,0 +1,636 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	neturl "net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultModule = "default"
+
+// Authentication modes of a module or target
+const (
+	authModeSession = "session"
+	authModeHeader  = "header"
+)
+
+var labelNameRE = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// config represents the contents of the configuration file
+type config struct {
+	Modules map[string]module `yaml:"modules"`
+	Targets []target          `yaml:"targets"`
+
+	// Discovery discovers targets from the managed devices of Citrix ADMs
+	Discovery []discoveryConfig `yaml:"adm_discovery"`
+
+	// Vault holds the settings of the vault secret provider
+	Vault *vaultConfig `yaml:"vault"`
+
+	// providers holds the secret providers by name, once the configuration has been validated
+	providers map[string]secretProvider
+}
+
+// module holds the credentials and options used when scraping a NetScaler, with at most one of password, password_file and password_secret.
+type module struct {
+	Username       string     `yaml:"username"`
+	Password       string     `yaml:"password"`
+	PasswordFile   string     `yaml:"password_file"`
+	PasswordSecret *secretRef `yaml:"password_secret"`
+	TLS            tlsConfig  `yaml:"tls_config"`
+	Collectors     []string   `yaml:"collectors"`
+
+	// ProbeTargets are regular expressions matching the hosts which may be probed with the module without being configured as targets
+	ProbeTargets []string `yaml:"probe_targets"`
+
+	// probeTargets holds the compiled ProbeTargets, once the configuration has been validated
+	probeTargets []*regexp.Regexp
+
+	// MaxConcurrentRequests limits the Nitro requests sent to each NetScaler at once, with 0 meaning no limit, and defaults to the max_concurrent_requests flag
+	MaxConcurrentRequests *int `yaml:"max_concurrent_requests"`
+
+	// AuthMode is session to log in and keep a session, or header to send the credentials with every request, and defaults to session
+	AuthMode string `yaml:"auth_mode"`
+
+	// Filters select which virtual servers, services and service groups are exported
+	Filters filters `yaml:"filters"`
+
+	// PageSize retrieves large configuration collections in pages of this many resources, with 0 disabling paging, and defaults to the page_size flag
+	PageSize *int `yaml:"page_size"`
+
+	// MaxResponseSize fails requests whose response is larger than this many bytes, with 0 meaning no limit, and defaults to the max_response_size flag
+	MaxResponseSize *int64 `yaml:"max_response_size"`
+
+	// MaxRetries retries requests which fail with a transient error up to this many times, with 0 disabling retries, and defaults to the max_retries flag
+	MaxRetries *int `yaml:"max_retries"`
+
+	// RetryBackoff is the wait before the first retry of a request, with 0 retrying at once, and defaults to the retry_backoff flag
+	RetryBackoff *time.Duration `yaml:"retry_backoff"`
+}
+
+// tlsConfig holds the TLS settings used when connecting to the NetScaler management interface
+type tlsConfig struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	MinVersion         string `yaml:"min_version"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// tlsVersions maps the accepted values of min_version to the TLS version
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// target is a NetScaler which is exported on /metrics, with TLS settings, filters and an authentication mode which replace those of its module.
+type target struct {
+	URL      string            `yaml:"url"`
+	Module   string            `yaml:"module"`
+	Labels   map[string]string `yaml:"labels"`
+	TLS      *tlsConfig        `yaml:"tls_config"`
+	Filters  filters           `yaml:"filters"`
+	AuthMode string            `yaml:"auth_mode"`
+
+	// ADM is the URL of the Citrix ADM which proxies requests to the target, if it can't be reached directly.  The host of the target URL must be its IP address as known to the ADM.
+	ADM string `yaml:"adm"`
+
+	// PollInterval polls the target in the background rather than when /metrics is scraped.  Defaults to the poll.interval flag.
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// loadConfig reads and parses the configuration file
+func loadConfig(filename string) (*config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading config file")
+	}
+
+	cfg := &config{}
+
+	err = yaml.UnmarshalStrict(content, cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing config file")
+	}
+
+	if cfg.Modules == nil {
+		cfg.Modules = map[string]module{}
+	}
+
+	return cfg, nil
+}
+
+// validate checks that the configuration is complete and consistent, and fills in defaults
+func (cfg *config) validate() error {
+	cfg.providers = map[string]secretProvider{}
+
+	if cfg.Vault != nil {
+		provider, err := newVaultProvider(*cfg.Vault)
+		if err != nil {
+			return fmt.Errorf("vault: %s", err)
+		}
+
+		cfg.providers["vault"] = provider
+	}
+
+	for name, m := range cfg.Modules {
+		if m.Username == "" {
+			return fmt.Errorf("module %q: username must be set", name)
+		}
+
+		err := m.validateCredentials(cfg.providers)
+		if err != nil {
+			return fmt.Errorf("module %q: %s", name, err)
+		}
+
+		for _, c := range m.Collectors {
+			if !isCollectorName(c) {
+				return fmt.Errorf("module %q: unknown collector %q; valid collectors are %v", name, c, collectorNames())
+			}
+		}
+
+		if !validAuthMode(m.AuthMode) {
+			return fmt.Errorf("module %q: unknown auth_mode %q; valid modes are session and header", name, m.AuthMode)
+		}
+
+		m.probeTargets = nil
+
+		for _, expr := range m.ProbeTargets {
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				return fmt.Errorf("module %q: invalid probe_targets: %s", name, err)
+			}
+
+			m.probeTargets = append(m.probeTargets, re)
+		}
+
+		_, err = m.TLS.tlsClientConfig()
+		if err != nil {
+			return fmt.Errorf("module %q: invalid tls_config: %s", name, err)
+		}
+
+		err = m.Filters.compile()
+		if err != nil {
+			return fmt.Errorf("module %q: invalid filters: %s", name, err)
+		}
+
+		if m.MaxConcurrentRequests != nil && *m.MaxConcurrentRequests < 0 {
+			return fmt.Errorf("module %q: max_concurrent_requests must not be negative", name)
+		}
+
+		if m.MaxConcurrentRequests == nil {
+			m.MaxConcurrentRequests = maxConcurrentRequests
+		}
+
+		if m.PageSize != nil && *m.PageSize < 0 {
+			return fmt.Errorf("module %q: page_size must not be negative", name)
+		}
+
+		if m.PageSize == nil {
+			m.PageSize = pageSize
+		}
+
+		if m.MaxResponseSize != nil && *m.MaxResponseSize < 0 {
+			return fmt.Errorf("module %q: max_response_size must not be negative", name)
+		}
+
+		if m.MaxResponseSize == nil {
+			m.MaxResponseSize = maxResponseSize
+		}
+
+		if m.MaxRetries != nil && *m.MaxRetries < 0 {
+			return fmt.Errorf("module %q: max_retries must not be negative", name)
+		}
+
+		if m.MaxRetries == nil {
+			m.MaxRetries = maxRetries
+		}
+
+		if m.RetryBackoff != nil && *m.RetryBackoff < 0 {
+			return fmt.Errorf("module %q: retry_backoff must not be negative", name)
+		}
+
+		if m.RetryBackoff == nil {
+			m.RetryBackoff = retryBackoff
+		}
+
+		cfg.Modules[name] = m
+	}
+
+	seen := map[string]bool{}
+
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+
+		if t.URL == "" {
+			return fmt.Errorf("target %d: url must be set", i+1)
+		}
+
+		u, err := neturl.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target %q: url must be of the form https://host", t.URL)
+		}
+
+		// URLs which differ only in scheme or a trailing slash are the same NetScaler, and would export the same series
+		if seen[instanceName(t.URL)] {
+			return fmt.Errorf("target %q: defined more than once", t.URL)
+		}
+		seen[instanceName(t.URL)] = true
+
+		if t.Module == "" {
+			t.Module = defaultModule
+		}
+
+		if _, ok := cfg.Modules[t.Module]; !ok {
+			return fmt.Errorf("target %q: unknown module %q", t.URL, t.Module)
+		}
+
+		for name := range t.Labels {
+			if !labelNameRE.MatchString(name) {
+				return fmt.Errorf("target %q: invalid label name %q", t.URL, name)
+			}
+
+			if reservedLabel(name) {
+				return fmt.Errorf("target %q: label %q is reserved for the exporter's own labels", t.URL, name)
+			}
+		}
+
+		if t.TLS != nil {
+			_, err = t.TLS.tlsClientConfig()
+			if err != nil {
+				return fmt.Errorf("target %q: invalid tls_config: %s", t.URL, err)
+			}
+		}
+
+		err = t.Filters.compile()
+		if err != nil {
+			return fmt.Errorf("target %q: invalid filters: %s", t.URL, err)
+		}
+
+		if !validAuthMode(t.AuthMode) {
+			return fmt.Errorf("target %q: unknown auth_mode %q; valid modes are session and header", t.URL, t.AuthMode)
+		}
+
+		if t.ADM != "" {
+			err = t.validateADM(cfg.Modules[t.Module])
+			if err != nil {
+				return fmt.Errorf("target %q: %s", t.URL, err)
+			}
+		}
+
+		if t.PollInterval < 0 {
+			return fmt.Errorf("target %q: poll_interval must not be negative", t.URL)
+		}
+	}
+
+	seenADMs := map[string]bool{}
+
+	for i := range cfg.Discovery {
+		d := &cfg.Discovery[i]
+
+		err := d.validate(cfg.Modules)
+		if err != nil {
+			return fmt.Errorf("adm_discovery %q: %s", d.ADM, err)
+		}
+
+		if seenADMs[d.ADM] {
+			return fmt.Errorf("adm_discovery %q: defined more than once", d.ADM)
+		}
+		seenADMs[d.ADM] = true
+	}
+
+	return nil
+}
+
+// validateCredentials checks that the module has exactly one source for its password, and that any environment variables and password file it refers to exist.
+func (m module) validateCredentials(providers map[string]secretProvider) error {
+	sources := 0
+
+	for _, set := range []bool{m.Password != "", m.PasswordFile != "", m.PasswordSecret != nil} {
+		if set {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		return errors.New("exactly one of password, password_file and password_secret must be set")
+	}
+
+	_, err := expandEnv(m.Username)
+	if err != nil {
+		return errors.Wrap(err, "invalid username")
+	}
+
+	_, err = expandEnv(m.Password)
+	if err != nil {
+		return errors.Wrap(err, "invalid password")
+	}
+
+	if m.PasswordFile != "" {
+		_, err = ioutil.ReadFile(m.PasswordFile)
+		if err != nil {
+			return errors.Wrap(err, "invalid password_file")
+		}
+	}
+
+	if s := m.PasswordSecret; s != nil {
+		if _, ok := providers[s.provider()]; !ok {
+			return fmt.Errorf("password_secret: secret provider %q is not configured", s.provider())
+		}
+
+		if s.Path == "" || s.Key == "" {
+			return errors.New("password_secret: path and key must be set")
+		}
+	}
+
+	return nil
+}
+
+// validateADM checks the settings of a target reached through an ADM, whose connection takes its TLS settings from the module.
+func (t target) validateADM(m module) error {
+	u, err := neturl.Parse(t.ADM)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("adm must be of the form https://host")
+	}
+
+	if t.TLS != nil {
+		return errors.New("tls_config can't be set on a target reached through adm; set it in the module instead")
+	}
+
+	if t.AuthMode == authModeHeader || (t.AuthMode == "" && m.AuthMode == authModeHeader) {
+		return errors.New("auth_mode header can't be used through adm")
+	}
+
+	return nil
+}
+
+// knownADM reports whether the ADM is used by any configured target or discovery, and so may be given in the adm parameter of a probe
+func (cfg *config) knownADM(adm string) bool {
+	adm = strings.TrimRight(adm, "/")
+
+	for _, t := range cfg.Targets {
+		if t.ADM != "" && strings.TrimRight(t.ADM, "/") == adm {
+			return true
+		}
+	}
+
+	for _, d := range cfg.Discovery {
+		if strings.TrimRight(d.ADM, "/") == adm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// instanceIP returns the address of the target, which identifies it to the ADM it is reached through
+func (t target) instanceIP() string {
+	u, err := neturl.Parse(t.URL)
+	if err != nil {
+		return instanceName(t.URL)
+	}
+
+	return u.Hostname()
+}
+
+// targets returns the configured targets followed by the discovered ones
+func (cfg *config) targets() []target {
+	return append(append([]target{}, cfg.Targets...), cfg.discoveredTargets()...)
+}
+
+// discoveredTargets returns the targets discovered from ADMs, leaving out those which are configured or already discovered.
+func (cfg *config) discoveredTargets() []target {
+	seen := map[string]bool{}
+	for _, t := range cfg.Targets {
+		seen[instanceName(t.URL)] = true
+	}
+
+	var targets []target
+
+	for _, d := range discoverers {
+		for _, t := range d.current() {
+			if seen[instanceName(t.URL)] {
+				continue
+			}
+			seen[instanceName(t.URL)] = true
+
+			targets = append(targets, t)
+		}
+	}
+
+	return targets
+}
+
+// findTarget returns the configured or discovered target matching the target parameter of a probe
+func (cfg *config) findTarget(name string) (target, bool) {
+	for _, t := range cfg.targets() {
+		if t.URL == name || instanceName(t.URL) == instanceName(targetURL(name)) {
+			return t, true
+		}
+	}
+
+	return target{}, false
+}
+
+// labelNames returns the sorted names of all static labels used by any target, including those of discovered targets.
+func (cfg *config) labelNames() []string {
+	names := map[string]bool{}
+
+	for _, t := range cfg.Targets {
+		for name := range t.Labels {
+			names[name] = true
+		}
+	}
+
+	for _, d := range cfg.Discovery {
+		for _, name := range discoveredLabels {
+			names[name] = true
+		}
+
+		for name := range d.Labels {
+			names[name] = true
+		}
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// exportedTargets returns the targets exported on /metrics, including those discovered so far.
+func (cfg *config) exportedTargets() []target {
+	var targets []target
+
+	for _, t := range cfg.targets() {
+		targets = append(targets, cfg.exported(t))
+	}
+
+	return targets
+}
+
+// exported returns the target as it is exported on /metrics, with empty values for labels it doesn't set.
+func (cfg *config) exported(t target) target {
+	labels := map[string]string{}
+	for _, name := range cfg.labelNames() {
+		labels[name] = t.Labels[name]
+	}
+
+	t.Labels = labels
+
+	return t
+}
+
+// tlsSettings returns the TLS settings used for the target
+func (t target) tlsSettings() tlsConfig {
+	if t.TLS != nil {
+		return *t.TLS
+	}
+
+	return cfg.Modules[t.Module].TLS
+}
+
+// authMode returns the authentication mode used for the target
+func (t target) authMode() string {
+	if t.AuthMode != "" {
+		return t.AuthMode
+	}
+
+	if m := cfg.Modules[t.Module]; m.AuthMode != "" {
+		return m.AuthMode
+	}
+
+	return authModeSession
+}
+
+// validAuthMode reports whether an authentication mode is known; an empty mode is the default
+func validAuthMode(mode string) bool {
+	return mode == "" || mode == authModeSession || mode == authModeHeader
+}
+
+// module returns the module used for the target, with any filters of the target replacing those of the module
+func (t target) module() module {
+	m := cfg.Modules[t.Module]
+
+	if t.Filters != nil {
+		m.Filters = t.Filters
+	}
+
+	return m
+}
+
+// tlsClientConfig builds the TLS configuration for the Nitro client, loading any CA bundle and client certificate from disk
+func (t tlsConfig) tlsClientConfig() (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.MinVersion != "" {
+		version, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown min_version %q; valid versions are TLS10, TLS11, TLS12 and TLS13", t.MinVersion)
+		}
+
+		c.MinVersion = version
+	}
+
+	if t.CAFile != "" {
+		ca, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading ca_file")
+		}
+
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no PEM certificates found in ca_file %q", t.CAFile)
+		}
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading client certificate")
+		}
+
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
+
+// logValues returns the TLS settings as key/value pairs for logging
+func (t tlsConfig) logValues() []interface{} {
+	return []interface{}{
+		"ca_file", t.CAFile,
+		"cert_file", t.CertFile,
+		"server_name", t.ServerName,
+		"min_version", t.MinVersion,
+		"insecure_skip_verify", t.InsecureSkipVerify,
+	}
+}
+
+// allowsProbe reports whether the module may be used to probe the NetScaler at the URL without it being configured as a target
+func (m module) allowsProbe(url string) bool {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return false
+	}
+
+	for _, re := range m.probeTargets {
+		if re.MatchString(u.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectorEnabled reports whether the module collects the given subsystem, which it does by default.
+func (m module) collectorEnabled(name string) bool {
+	if len(m.Collectors) == 0 {
+		return true
+	}
+
+	return contains(m.Collectors, name)
+}
+
+func isCollectorName(name string) bool {
+	for _, s := range subsystems {
+		if s.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectorNames returns the names of all the subsystems which can be collected
+func collectorNames() []string {
+	var names []string
+
+	for _, s := range subsystems {
+		names = append(names, s.name)
+	}
+
+	return names
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// defaultRefreshInterval is how often the inventory of an ADM is read if the discovery doesn't set it
+const defaultRefreshInterval = 5 * time.Minute
+
+// discoveredLabels are the labels given to every discovered target from the ADM inventory
+var discoveredLabels = []string{"site", "type", "ha_role"}
+
+var (
+	discoveredTargets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "netscaler_adm_discovered_targets",
+			Help: "Number of targets discovered from the managed devices of the ADM at its last successful refresh.",
+		},
+		[]string{
+			"adm",
+		},
+	)
+
+	discoveryFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "netscaler_adm_discovery_failures_total",
+			Help: "Number of refreshes of the ADM inventory which failed; the targets discovered before are kept.",
+		},
+		[]string{
+			"adm",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(discoveredTargets, discoveryFailures)
+}
+
+// discoveryConfig discovers the instances managed by a Citrix ADM as targets, which are reached through the ADM using the credentials of the module
+type discoveryConfig struct {
+	ADM    string            `yaml:"adm"`
+	Module string            `yaml:"module"`
+	Labels map[string]string `yaml:"labels"`
+
+	// Types only discovers instances of these types, such as nsvpx and nsmpx.  Every type is discovered if omitted.
+	Types []string `yaml:"types"`
+
+	// RefreshInterval is how often the inventory is read.  Defaults to 5m.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// validate checks the discovery settings, and fills in defaults
+func (d *discoveryConfig) validate(modules map[string]module) error {
+	u, err := neturl.Parse(d.ADM)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("adm must be of the form https://host")
+	}
+
+	if d.Module == "" {
+		d.Module = defaultModule
+	}
+
+	m, ok := modules[d.Module]
+	if !ok {
+		return fmt.Errorf("unknown module %q", d.Module)
+	}
+
+	if m.AuthMode == authModeHeader {
+		return errors.New("auth_mode header can't be used through adm")
+	}
+
+	for name := range d.Labels {
+		if !labelNameRE.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+
+		if reservedLabel(name) || contains(discoveredLabels, name) {
+			return fmt.Errorf("label %q is reserved for the exporter's own labels", name)
+		}
+	}
+
+	if d.RefreshInterval < 0 {
+		return errors.New("refresh_interval must not be negative")
+	}
+
+	if d.RefreshInterval == 0 {
+		d.RefreshInterval = defaultRefreshInterval
+	}
+
+	return nil
+}
+
+// discoverer keeps the targets discovered from one ADM up to date
+type discoverer struct {
+	config discoveryConfig
+
+	mu      sync.RWMutex
+	targets []target
+}
+
+// discoverers holds the discoverer of each ADM, once discovery has started
+var discoverers []*discoverer
+
+// startDiscovery starts discovering targets from every ADM in the configuration
+func startDiscovery() {
+	for _, d := range cfg.Discovery {
+		discoverers = append(discoverers, &discoverer{config: d})
+	}
+
+	// Every discoverer reads the targets of the others, so they are only started once the list is complete
+	for _, d := range discoverers {
+		level.Info(logger).Log("msg", "Discovering targets from ADM", "adm", d.config.ADM, "module", d.config.Module, "interval", d.config.RefreshInterval)
+
+		go d.run()
+	}
+}
+
+// run reads the inventory immediately and then every refresh interval, until the exporter exits
+func (d *discoverer) run() {
+	d.refresh()
+
+	ticker := time.NewTicker(d.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		d.refresh()
+	}
+}
+
+// refresh replaces the discovered targets with those in the inventory of the ADM, keeping them if it can't be read, and polls them to match.
+func (d *discoverer) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.RefreshInterval)
+	defer cancel()
+
+	targets, err := d.discover(ctx)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error discovering targets from ADM", "err", err, "adm", d.config.ADM)
+		discoveryFailures.WithLabelValues(d.config.ADM).Inc()
+		return
+	}
+
+	d.mu.Lock()
+	previous := d.targets
+	d.targets = targets
+	d.mu.Unlock()
+
+	discoveredTargets.WithLabelValues(d.config.ADM).Set(float64(len(targets)))
+
+	level.Info(logger).Log("msg", "Discovered targets from ADM", "adm", d.config.ADM, "targets", len(targets))
+
+	syncPollers(previous, targets)
+}
+
+// discover reads the managed devices of the ADM, and returns the targets for them
+func (d *discoverer) discover(ctx context.Context) ([]target, error) {
+	client, err := clients.get(target{
+		URL:    d.config.ADM,
+		Module: d.config.Module,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.FetchContext(ctx, netscaler.ManagedDeviceResource, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := result.([]netscaler.ManagedDevice)
+	sites := d.sites(ctx, client)
+
+	var targets []target
+
+	for _, device := range devices {
+		if device.IPAddress == "" {
+			continue
+		}
+
+		if len(d.config.Types) > 0 && !contains(d.config.Types, device.Type) {
+			continue
+		}
+
+		labels := map[string]string{}
+		for name, value := range d.config.Labels {
+			labels[name] = value
+		}
+
+		site, ok := sites[device.DatacenterID]
+		if !ok {
+			site = device.DatacenterID
+		}
+
+		labels["site"] = site
+		labels["type"] = device.Type
+		labels["ha_role"] = haRole(device.HAMasterState)
+
+		host := device.IPAddress
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		targets = append(targets, target{
+			URL:    "https://" + host,
+			Module: d.config.Module,
+			Labels: labels,
+			ADM:    d.config.ADM,
+		})
+	}
+
+	return targets, nil
+}
+
+// sites returns the names of the sites defined in the ADM by ID.
+func (d *discoverer) sites(ctx context.Context, client *netscaler.NitroClient) map[string]string {
+	sites := map[string]string{}
+
+	result, err := client.FetchContext(ctx, netscaler.MPSDatacenterResource, "", nil)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Error reading sites from ADM; labelling targets with the site ID", "err", err, "adm", d.config.ADM)
+		return sites
+	}
+
+	for _, dc := range result.([]netscaler.MPSDatacenter) {
+		sites[dc.ID] = dc.Name
+	}
+
+	return sites
+}
+
+// current returns the targets discovered at the last successful refresh
+func (d *discoverer) current() []target {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.targets
+}
+
+// haRole returns the ha_role label of a device from its HA state in the ADM; devices which aren't in an HA pair are standalone
+func haRole(state string) string {
+	if state == "" {
+		return "standalone"
+	}
+
+	return strings.ToLower(state)
+}
+
+// sdGroup is a target group in the format of Prometheus HTTP service discovery
+type sdGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// sdHandler serves the targets discovered from ADMs for Prometheus HTTP service discovery, to be scraped through /probe.
+func sdHandler(w http.ResponseWriter, r *http.Request) {
+	groups := []sdGroup{}
+
+	for _, t := range cfg.discoveredTargets() {
+		labels := map[string]string{
+			"__param_module":       t.Module,
+			"__param_adm":          t.ADM,
+			"__meta_netscaler_adm": t.ADM,
+			"__meta_netscaler_url": t.URL,
+		}
+
+		for name, value := range t.Labels {
+			labels["__meta_netscaler_label_"+name] = value
+		}
+
+		groups = append(groups, sdGroup{
+			Targets: []string{instanceName(t.URL)},
+			Labels:  labels,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(w).Encode(groups)
+	if err != nil {
+		level.Error(logger).Log("msg", "Error writing discovered targets", "err", err)
+	}
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+var (
+	nsUp = prometheus.NewDesc(
+		"netscaler_up",
+		"Whether the NetScaler could be scraped; 0 if every collector failed.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	scrapeDuration = prometheus.NewDesc(
+		"netscaler_scrape_duration_seconds",
+		"Time taken to scrape the NetScaler.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	collectorSuccess = prometheus.NewDesc(
+		"netscaler_collector_success",
+		"Whether a collector succeeded in retrieving its data from the NetScaler.",
+		[]string{
+			"ns_instance",
+			"collector",
+		},
+		nil,
+	)
+
+	collectorDuration = prometheus.NewDesc(
+		"netscaler_collector_duration_seconds",
+		"Time taken by a collector to retrieve its data from the NetScaler.",
+		[]string{
+			"ns_instance",
+			"collector",
+		},
+		nil,
+	)
+
+	authFailures = prometheus.NewDesc(
+		"netscaler_auth_failures_total",
+		"Number of times the NetScaler has rejected the credentials of the exporter.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	authBackoffUntil = prometheus.NewDesc(
+		"netscaler_auth_backoff_until_seconds",
+		"Time until which the exporter won't authenticate to the NetScaler after its credentials were rejected; 0 if it isn't backing off.",
+		[]string{
+			"ns_instance",
+		},
+		nil,
+	)
+
+	skippedValues = prometheus.NewDesc(
+		"netscaler_skipped_values",
+		"Number of values in the scrape which were not exported because the NetScaler did not return them (missing) or returned something other than a number (invalid).",
+		[]string{
+			"ns_instance",
+			"reason",
+		},
+		nil,
+	)
+)
+
+// subsystem is a part of the NetScaler which is collected, and reported on, separately
+type subsystem struct {
+	name    string
+	collect func(e *Exporter, ch chan<- prometheus.Metric) error
+}
+
+// subsystems lists everything the exporter can collect
+var subsystems = []subsystem{
+	{"license", (*Exporter).collectLicense},
+	{"ns", (*Exporter).collectNS},
+	{"interface", (*Exporter).collectInterfaces},
+	{"lbvserver", (*Exporter).collectVirtualServers},
+	{"service", (*Exporter).collectServices},
+	{"servicegroup", (*Exporter).collectServiceGroups},
+}
+
+// collectorFlags holds the -collector.<name> and -no-collector.<name> flags of each subsystem
+var collectorFlags = map[string]struct {
+	enable  *bool
+	disable *bool
+}{}
+
+func init() {
+	for _, s := range subsystems {
+		collectorFlags[s.name] = struct {
+			enable  *bool
+			disable *bool
+		}{
+			enable:  flag.Bool("collector."+s.name, true, "Enable the "+s.name+" collector"),
+			disable: flag.Bool("no-collector."+s.name, false, "Disable the "+s.name+" collector"),
+		}
+	}
+}
+
+// collectorFlagEnabled reports whether a subsystem is enabled on the command line
+func collectorFlagEnabled(name string) bool {
+	f := collectorFlags[name]
+
+	return *f.enable && !*f.disable
+}
+
+// enabledCollectors returns the subsystems enabled on the command line and by the module, limited to any collect[] parameters.
+func enabledCollectors(m module, collect []string) (map[string]bool, error) {
+	for _, name := range collect {
+		if !isCollectorName(name) {
+			return nil, fmt.Errorf("unknown collector %q; valid collectors are %v", name, collectorNames())
+		}
+	}
+
+	enabled := map[string]bool{}
+
+	for _, s := range subsystems {
+		if !collectorFlagEnabled(s.name) || !m.collectorEnabled(s.name) {
+			continue
+		}
+
+		if len(collect) > 0 && !contains(collect, s.name) {
+			continue
+		}
+
+		enabled[s.name] = true
+	}
+
+	return enabled, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errorHint suggests the likely cause of a failed collector from the Nitro error, or returns an empty string if there is nothing to suggest
+func errorHint(err error) string {
+	switch {
+	case netscaler.IsAuthError(err):
+		return "check the username and password of the module"
+	case netscaler.IsPermissionDenied(err):
+		return "the command policy of the user does not permit the request"
+	case netscaler.IsNotFound(err):
+		return "the resource does not exist; the feature may not be licensed or enabled"
+	default:
+		return ""
+	}
+}
+
+// Exporter represents the metrics exported to Prometheus for a scrape, built afresh from its Nitro responses and cancelled with its context.
+type Exporter struct {
+	ctx        context.Context
+	client     *netscaler.NitroClient
+	nsInstance string
+	module     module
+	collectors map[string]bool
+
+	mu      sync.Mutex
+	missing int
+	invalid int
+}
+
+// NewExporter initialises the exporter for the NetScaler at the given URL, running only the given collectors.
+func NewExporter(ctx context.Context, nsClient *netscaler.NitroClient, url string, m module, collectors map[string]bool) *Exporter {
+	return &Exporter{
+		ctx:        ctx,
+		client:     nsClient,
+		nsInstance: instanceName(url),
+		module:     m,
+		collectors: collectors,
+	}
+}
+
+// Describe implements Collector
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nsUp
+	ch <- scrapeDuration
+	ch <- collectorSuccess
+	ch <- collectorDuration
+	ch <- skippedValues
+	ch <- authFailures
+	ch <- authBackoffUntil
+
+	ch <- modelID
+	ch <- mgmtCPUUsage
+	ch <- memUsage
+	ch <- pktCPUUsage
+	ch <- flashPartitionUsage
+	ch <- varPartitionUsage
+	ch <- rxMbPerSec
+	ch <- txMbPerSec
+	ch <- httpRequestsRate
+	ch <- httpResponsesRate
+	ch <- tcpCurrentClientConnections
+	ch <- tcpCurrentClientConnectionsEstablished
+	ch <- tcpCurrentServerConnections
+	ch <- tcpCurrentServerConnectionsEstablished
+
+	ch <- interfacesRxBytesPerSecond
+	ch <- interfacesTxBytesPerSecond
+	ch <- interfacesRxPacketsPerSecond
+	ch <- interfacesTxPacketsPerSecond
+	ch <- interfacesJumboPacketsRxPerSecond
+	ch <- interfacesJumboPacketsTxPerSecond
+	ch <- interfacesErrorPacketsRxPerSecond
+
+	ch <- virtualServersWaitingRequests
+	ch <- virtualServersHealth
+	ch <- virtualServersInactiveServices
+	ch <- virtualServersActiveServices
+	ch <- virtualServersTotalHits
+	ch <- virtualServersHitsRate
+	ch <- virtualServersTotalRequests
+	ch <- virtualServersRequestsRate
+	ch <- virtualServersTotalResponses
+	ch <- virtualServersReponsesRate
+	ch <- virtualServersTotalRequestBytes
+	ch <- virtualServersRequestBytesRate
+	ch <- virtualServersTotalResponseBytes
+	ch <- virtualServersReponseBytesRate
+	ch <- virtualServersCurrentClientConnections
+	ch <- virtualServersCurrentServerConnections
+
+	ch <- servicesThroughput
+	ch <- servicesThroughputRate
+	ch <- servicesAvgTTFB
+	ch <- servicesState
+	ch <- servicesTotalRequests
+	ch <- servicesRequestsRate
+	ch <- servicesTotalResponses
+	ch <- servicesResponsesRate
+	ch <- servicesTotalRequestBytes
+	ch <- servicesRequestBytesRate
+	ch <- servicesTotalResponseBytes
+	ch <- servicesResponseBytesRate
+	ch <- servicesCurrentClientConns
+	ch <- servicesSurgeCount
+	ch <- servicesCurrentServerConns
+	ch <- servicesServerEstablishedConnections
+	ch <- servicesCurrentReusePool
+	ch <- servicesMaxClients
+	ch <- servicesCurrentLoad
+	ch <- servicesVirtualServerServiceHits
+	ch <- servicesVirtualServerServiceHitsRate
+	ch <- servicesActiveTransactions
+
+	ch <- serviceGroupsState
+	ch <- serviceGroupsAvgTTFB
+	ch <- serviceGroupsTotalRequests
+	ch <- serviceGroupsRequestsRate
+	ch <- serviceGroupsTotalResponses
+	ch <- serviceGroupsResponsesRate
+	ch <- serviceGroupsTotalRequestBytes
+	ch <- serviceGroupsRequestBytesRate
+	ch <- serviceGroupsTotalResponseBytes
+	ch <- serviceGroupsResponseBytesRate
+	ch <- serviceGroupsCurrentClientConnections
+	ch <- serviceGroupsSurgeCount
+	ch <- serviceGroupsCurrentServerConnections
+	ch <- serviceGroupsServerEstablishedConnections
+	ch <- serviceGroupsCurrentReusePool
+	ch <- serviceGroupsMaxClients
+}
+
+// Collect is initiated by the Prometheus handler and gathers the metrics, collecting subsystems in parallel.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	scrapeStart := time.Now()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		failed    int
+	)
+
+	// Nothing is collected while backing off after the NetScaler rejected the credentials
+	backoffErr := e.client.AuthBackoff(e.ctx)
+	if backoffErr != nil {
+		level.Error(logger).Log("msg", "Skipping scrape", "err", backoffErr, "ns_instance", e.nsInstance, "hint", errorHint(backoffErr))
+	}
+
+	for _, s := range subsystems {
+		if !e.collectors[s.name] || backoffErr != nil {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(s subsystem) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := s.collect(e, ch)
+			duration := time.Since(start).Seconds()
+
+			success := 1.0
+			if err != nil {
+				keyvals := []interface{}{"msg", err, "ns_instance", e.nsInstance, "collector", s.name}
+				if hint := errorHint(err); hint != "" {
+					keyvals = append(keyvals, "hint", hint)
+				}
+
+				level.Error(logger).Log(keyvals...)
+				success = 0
+			}
+
+			mu.Lock()
+			if err != nil {
+				failed++
+			} else {
+				succeeded++
+			}
+			mu.Unlock()
+
+			ch <- prometheus.MustNewConstMetric(
+				collectorDuration, prometheus.GaugeValue, duration, e.nsInstance, s.name,
+			)
+
+			ch <- prometheus.MustNewConstMetric(
+				collectorSuccess, prometheus.GaugeValue, success, e.nsInstance, s.name,
+			)
+		}(s)
+	}
+
+	wg.Wait()
+
+	// The NetScaler is only considered down if nothing at all could be collected from it
+	up := 1.0
+	if backoffErr != nil || (failed > 0 && succeeded == 0) {
+		up = 0
+	}
+
+	backoffUntil := 0.0
+	if until := e.client.AuthBackoffUntil(); !until.IsZero() {
+		backoffUntil = float64(until.UnixNano()) / 1e9
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		authFailures, prometheus.CounterValue, float64(e.client.AuthFailures()), e.nsInstance,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		authBackoffUntil, prometheus.GaugeValue, backoffUntil, e.nsInstance,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		nsUp, prometheus.GaugeValue, up, e.nsInstance,
+	)
+
+	e.mu.Lock()
+	missing, invalid := e.missing, e.invalid
+	e.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(
+		skippedValues, prometheus.GaugeValue, float64(missing), e.nsInstance, "missing",
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		skippedValues, prometheus.GaugeValue, float64(invalid), e.nsInstance, "invalid",
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		scrapeDuration, prometheus.GaugeValue, time.Since(scrapeStart).Seconds(), e.nsInstance,
+	)
+}
+
+// number exports a value from a Nitro response, skipping and counting one which is missing or not a number.
+func (e *Exporter) number(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, n netscaler.Number, labelValues ...string) {
+	value, ok := n.Float64()
+	if ok {
+		ch <- prometheus.MustNewConstMetric(desc, valueType, value, labelValues...)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if n.Present() {
+		e.invalid++
+	} else {
+		e.missing++
+	}
+}
+
+// state exports 1 if a service or service group member is UP and 0 otherwise, skipping and counting a state missing from the response
+func (e *Exporter) state(ch chan<- prometheus.Metric, desc *prometheus.Desc, state string, labelValues ...string) {
+	if state == "" {
+		e.mu.Lock()
+		e.missing++
+		e.mu.Unlock()
+
+		return
+	}
+
+	value := 0.0
+
+	if state == "UP" {
+		value = 1.0
+	}
+
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+}
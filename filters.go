@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+)
+
+// filterTypes lists the entities which can be filtered by name
+var filterTypes = []string{"lbvserver", "service", "servicegroup"}
+
+// nitroRegexRE matches the regular expressions which the NetScaler interprets as Go does, and so can be passed on in a Nitro filter.
+var nitroRegexRE = regexp.MustCompile(`^[a-zA-Z0-9_.^$*+?|()\[\] -]+$`)
+
+// filter selects entities whose names match include, when set, and don't match exclude, anywhere in the name.
+type filter struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// filters holds the filter for each type of entity
+type filters map[string]filter
+
+// compile checks the filters and compiles their regular expressions
+func (f filters) compile() error {
+	for entity, fl := range f {
+		if !contains(filterTypes, entity) {
+			return fmt.Errorf("unknown filter %q; valid filters are %v", entity, filterTypes)
+		}
+
+		var err error
+
+		if fl.Include != "" {
+			fl.include, err = regexp.Compile(fl.Include)
+			if err != nil {
+				return fmt.Errorf("%s: invalid include: %s", entity, err)
+			}
+		}
+
+		if fl.Exclude != "" {
+			fl.exclude, err = regexp.Compile(fl.Exclude)
+			if err != nil {
+				return fmt.Errorf("%s: invalid exclude: %s", entity, err)
+			}
+		}
+
+		f[entity] = fl
+	}
+
+	return nil
+}
+
+// matches reports whether the named entity should be exported
+func (f filters) matches(entity string, name string) bool {
+	fl, ok := f[entity]
+	if !ok {
+		return true
+	}
+
+	if fl.include != nil && !fl.include.MatchString(name) {
+		return false
+	}
+
+	if fl.exclude != nil && fl.exclude.MatchString(name) {
+		return false
+	}
+
+	return true
+}
+
+// nitroQuery returns a Nitro query for the include expression of the entity, which is empty if the exporter must filter the response itself.
+func (f filters) nitroQuery(entity string, property string) *netscaler.Query {
+	fl, ok := f[entity]
+	if !ok || fl.Include == "" || !nitroRegexRE.MatchString(fl.Include) || strings.Contains(fl.Include, "(?") {
+		return netscaler.NewQuery()
+	}
+
+	return netscaler.NewQuery().FilterRegex(property, fl.Include)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// reservedLabels are the names of the labels of the exporter's own metrics, which would be duplicated if a target had a static label of the same name
+var reservedLabels = []string{
+	"alias",
+	"collector",
+	"interface",
+	"member",
+	"ns_instance",
+	"port",
+	"reason",
+	"service",
+	"servicegroup",
+	"virtual_server",
+}
+
+// reservedLabel reports whether a static label can't be given the name, because the exporter or Prometheus uses it
+func reservedLabel(name string) bool {
+	return strings.HasPrefix(name, "__") || contains(reservedLabels, name)
+}
+
+// labelGatherer adds the static labels of a target to every metric gathered from it
+type labelGatherer struct {
+	gatherer prometheus.Gatherer
+	labels   map[string]string
+}
+
+// Gather implements prometheus.Gatherer
+func (g labelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+
+	if len(g.labels) == 0 {
+		return mfs, err
+	}
+
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			for name, value := range g.labels {
+				m.Label = append(m.Label, &dto.LabelPair{
+					Name:  proto.String(name),
+					Value: proto.String(value),
+				})
+			}
+
+			sort.Sort(prometheus.LabelPairSorter(m.Label))
+		}
+	}
+
+	return mfs, err
+}
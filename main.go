@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
-
-	"github.com/rokett/citrix-netscaler-exporter/netscaler"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,1975 +19,232 @@ import (
 )
 
 var (
-	app        = "Citrix-NetScaler-Exporter"
-	version    string
-	build      string
-	url        = flag.String("url", "", "Base URL of the NetScaler management interface.  Normally something like https://my-netscaler.something.x")
-	username   = flag.String("username", "", "Username with which to connect to the NetScaler API")
-	password   = flag.String("password", "", "Password with which to connect to the NetScaler API")
-	bindPort   = flag.Int("bind_port", 9280, "Port to bind the exporter endpoint to")
-	versionFlg = flag.Bool("version", false, "Display application version")
-	logger     log.Logger
-
-	nsInstance string
-
-	modelID = prometheus.NewDesc(
-		"model_id",
-		"NetScaler model - reflects the bandwidth available; for example VPX 10 would report as 10.",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	mgmtCPUUsage = prometheus.NewDesc(
-		"mgmt_cpu_usage",
-		"Current CPU utilisation for management",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	pktCPUUsage = prometheus.NewDesc(
-		"pkt_cpu_usage",
-		"Current CPU utilisation for packet engines, excluding management",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	memUsage = prometheus.NewDesc(
-		"mem_usage",
-		"Current memory utilisation",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	flashPartitionUsage = prometheus.NewDesc(
-		"flash_partition_usage",
-		"Used space in /flash partition of the disk, as a percentage.",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	varPartitionUsage = prometheus.NewDesc(
-		"var_partition_usage",
-		"Used space in /var partition of the disk, as a percentage. ",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	rxMbPerSec = prometheus.NewDesc(
-		"received_mb_per_second",
-		"Number of Megabits received by the NetScaler appliance per second",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	txMbPerSec = prometheus.NewDesc(
-		"transmit_mb_per_second",
-		"Number of Megabits transmitted by the NetScaler appliance per second",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	httpRequestsRate = prometheus.NewDesc(
-		"http_requests_rate",
-		"HTTP requests received per second",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	httpResponsesRate = prometheus.NewDesc(
-		"http_responses_rate",
-		"HTTP requests sent per second",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	tcpCurrentClientConnections = prometheus.NewDesc(
-		"tcp_current_client_connections",
-		"Client connections, including connections in the Opening, Established, and Closing state.",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	tcpCurrentClientConnectionsEstablished = prometheus.NewDesc(
-		"tcp_current_client_connections_established",
-		"Current client connections in the Established state, which indicates that data transfer can occur between the NetScaler and the client.",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	tcpCurrentServerConnections = prometheus.NewDesc(
-		"tcp_current_server_connections",
-		"Server connections, including connections in the Opening, Established, and Closing state.",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	tcpCurrentServerConnectionsEstablished = prometheus.NewDesc(
-		"tcp_current_server_connections_established",
-		"Current server connections in the Established state, which indicates that data transfer can occur between the NetScaler and the server.",
-		[]string{
-			"ns_instance",
-		},
-		nil,
-	)
-
-	interfacesRxBytesPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_received_bytes_per_second",
-			Help: "Number of bytes received per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	interfacesTxBytesPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_transmitted_bytes_per_second",
-			Help: "Number of bytes transmitted per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	interfacesRxPacketsPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_received_packets_per_second",
-			Help: "Number of packets received per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	interfacesTxPacketsPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_transmitted_packets_per_second",
-			Help: "Number of packets transmitted per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	interfacesJumboPacketsRxPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_jumbo_packets_received_per_second",
-			Help: "Number of bytes received per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	interfacesJumboPacketsTxPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_jumbo_packets_transmitted_per_second",
-			Help: "Number of jumbo packets transmitted per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	interfacesErrorPacketsRxPerSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "interfaces_error_packets_received_per_second",
-			Help: "Number of error packets received per second by specific interfaces",
-		},
-		[]string{
-			"ns_instance",
-			"interface",
-			"alias",
-		},
-	)
-
-	virtualServersWaitingRequests = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_waiting_requests",
-			Help: "Number of requests waiting on a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersHealth = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_health",
-			Help: "Percentage of UP services bound to a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersInactiveServices = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_inactive_services",
-			Help: "Number of inactive services bound to a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersActiveServices = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_active_services",
-			Help: "Number of active services bound to a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersTotalHits = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "virtual_servers_total_hits",
-			Help: "Total virtual server hits",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersHitsRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_hits_rate",
-			Help: "Number of hits/second to a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersTotalRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "virtual_servers_total_requests",
-			Help: "Total virtual server requests",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersRequestsRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_requests_rate",
-			Help: "Number of requests/second to a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersTotalResponses = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "virtual_servers_total_responses",
-			Help: "Total virtual server responses",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersReponsesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_responses_rate",
-			Help: "Number of responses/second from a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersTotalRequestBytes = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "virtual_servers_total_request_bytes",
-			Help: "Total virtual server request bytes",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersRequestBytesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_request_bytes_rate",
-			Help: "Number of request bytes/second to a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersTotalResponseBytes = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "virtual_servers_total_response_bytes",
-			Help: "Total virtual server response bytes",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersReponseBytesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_reponse_bytes_rate",
-			Help: "Number of response bytes/second from a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersCurrentClientConnections = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_current_client_connections",
-			Help: "Number of current client connections on a specific virtual server",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	virtualServersCurrentServerConnections = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "virtual_servers_current_server_connections",
-			Help: "Number of current connections to the actual servers behind the specific virtual server.",
-		},
-		[]string{
-			"ns_instance",
-			"virtual_server",
-		},
-	)
-
-	servicesThroughput = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "service_throughput",
-			Help: "Number of bytes received or sent by this service (Mbps)",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesThroughputRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_throughput_rate",
-			Help: "Rate (/s) counter for throughput",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesAvgTTFB = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_average_time_to_first_byte",
-			Help: "Average TTFB between the NetScaler appliance and the server.TTFB is the time interval between sending the request packet to a service and receiving the first response from the service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesState = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_state",
-			Help: "Current state of the service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesTotalRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "service_total_requests",
-			Help: "Total number of requests received on this service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesRequestsRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_request_rate",
-			Help: "Rate (/s) counter for totalrequests",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesTotalResponses = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "service_total_responses",
-			Help: "Total number of responses received on this service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesResponsesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_responses_rate",
-			Help: "Rate (/s) counter for totalresponses",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesTotalRequestBytes = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "service_total_request_bytes",
-			Help: "Total number of request bytes received on this service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesRequestBytesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_request_bytes_rate",
-			Help: "Rate (/s) counter for totalrequestbytes",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesTotalResponseBytes = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "service_total_response_bytes",
-			Help: "Total number of response bytes received on this service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesResponseBytesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_response_bytes_rate",
-			Help: "Rate (/s) counter for totalresponsebytes",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesCurrentClientConns = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_current_client_connections",
-			Help: "Number of current client connections",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesSurgeCount = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_surge_count",
-			Help: "Number of requests in the surge queue",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesCurrentServerConns = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_current_server_connections",
-			Help: "Number of current connections to the actual servers",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesServerEstablishedConnections = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_server_established_connections",
-			Help: "Number of server connections in ESTABLISHED state",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesCurrentReusePool = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_current_reuse_pool",
-			Help: "Number of requests in the idle queue/reuse pool.",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesMaxClients = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_max_clients",
-			Help: "Maximum open connections allowed on this service",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesCurrentLoad = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_current_load",
-			Help: "Load on the service that is calculated from the bound load based monitor",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesVirtualServerServiceHits = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "service_virtual_server_service_hits",
-			Help: "Number of times that the service has been provided",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesVirtualServerServiceHitsRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_virtual_server_service_hits_rate",
-			Help: "Rate (/s) counter for vsvrservicehits",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	servicesActiveTransactions = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "service_active_transactions",
-			Help: "Number of active transactions handled by this service. (Including those in the surge queue.) Active Transaction means number of transactions currently served by the server including those waiting in the SurgeQ",
-		},
-		[]string{
-			"ns_instance",
-			"service",
-		},
-	)
-
-	serviceGroupsState = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_state",
-			Help: "Current state of the server",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsAvgTTFB = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_average_time_to_first_byte",
-			Help: "Average TTFB between the NetScaler appliance and the server.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsTotalRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "servicegroup_total_requests",
-			Help: "Total number of requests received on this service",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsRequestsRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_requests_rate",
-			Help: "Rate (/s) counter for totalrequests",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsTotalResponses = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "servicegroup_total_responses",
-			Help: "Number of responses received on this service.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsResponsesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_responses_rate",
-			Help: "Rate (/s) counter for totalresponses",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsTotalRequestBytes = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "servicegroup_total_request_bytes",
-			Help: "Total number of request bytes received on this service",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsRequestBytesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_request_bytes_rate",
-			Help: "Rate (/s) counter for totalrequestbytes",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsTotalResponseBytes = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "servicegroup_total_response_bytes",
-			Help: "Number of response bytes received by this service",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsResponseBytesRate = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_response_bytes_rate",
-			Help: "Rate (/s) counter for totalresponsebytes",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsCurrentClientConnections = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_current_client_connections",
-			Help: "Number of current client connections.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsSurgeCount = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_surge_count",
-			Help: "Number of requests in the surge queue.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsCurrentServerConnections = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_current_server_connections",
-			Help: "Number of current connections to the actual servers behind the virtual server.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsServerEstablishedConnections = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_server_established_connections",
-			Help: "Number of server connections in ESTABLISHED state.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsCurrentReusePool = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_current_reuse_pool",
-			Help: "Number of requests in the idle queue/reuse pool.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
-
-	serviceGroupsMaxClients = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "servicegroup_max_clients",
-			Help: "Maximum open connections allowed on this service.",
-		},
-		[]string{
-			"ns_instance",
-			"servicegroup",
-			"member",
-		},
-	)
+	app          = "Citrix-NetScaler-Exporter"
+	version      string
+	build        string
+	url          = flag.String("url", "", "Base URL of the NetScaler management interface.  Normally something like https://my-netscaler.something.x.  If set, the NetScaler is exported on /metrics")
+	username     = flag.String("username", "", "Username with which to connect to the NetScaler API.  Overrides the username of the default module")
+	password     = flag.String("password", "", "Password with which to connect to the NetScaler API.  Overrides the password of the default module")
+	probeTargets = flag.String("probe.targets", "", "Regular expression matching the hosts which may be probed with the default module without being configured as targets.  It must match the whole host")
+	passwordFile = flag.String("password_file", "", "File containing the password with which to connect to the NetScaler API, which is read again if the password is rejected.  Overrides the password of the default module")
+	configFile   = flag.String("config.file", "", "Path to the YAML configuration file declaring targets and modules")
+	bindPort     = flag.Int("bind_port", 9280, "Port to bind the exporter endpoint to")
+	versionFlg   = flag.Bool("version", false, "Display application version")
+	logger       log.Logger
+	cfg          *config
+
+	pollInterval          = flag.Duration("poll.interval", 0, "Poll every target in the background at this interval, and serve the results on /metrics, unless set by the target.  Targets are scraped when /metrics is scraped if not set")
+	pollMaxAge            = flag.Duration("poll.max_age", 0, "Stop exporting the results of a poll once they are this old.  Defaults to three poll intervals")
+	pollTimeout           = flag.Duration("poll.timeout", time.Minute, "Cancel a background poll which hasn't finished after this long, or when the next poll is due if that is sooner")
+	scrapeTimeout         = flag.Duration("scrape_timeout", 30*time.Second, "Cancel Nitro API requests still in flight after this long when a scrape doesn't give the Prometheus scrape timeout.  0 means no limit")
+	scrapeTimeoutOffset   = flag.Duration("scrape_timeout_offset", 500*time.Millisecond, "Time subtracted from the Prometheus scrape timeout, after which requests still in flight are cancelled and the metrics collected so far are returned")
+	pageSize              = flag.Int("page_size", 0, "Retrieve configuration collections with more than this many resources in pages of this size, unless set by the module.  Collections are retrieved in one response if not set")
+	maxResponseSize       = flag.Int64("max_response_size", 0, "Fail Nitro API requests whose response is larger than this many bytes, unless set by the module.  Responses are not limited if not set")
+	maxConcurrentRequests = flag.Int("max_concurrent_requests", 4, "Maximum number of Nitro API requests sent to each NetScaler at the same time, unless set by the module.  0 means no limit")
+	maxRetries            = flag.Int("max_retries", 2, "Maximum number of times a Nitro API request or login which fails with a transient error is retried, unless set by the module.  0 disables retries")
+	retryBackoff          = flag.Duration("retry_backoff", 200*time.Millisecond, "Wait before the first retry of a Nitro API request, doubled for each further retry and jittered, unless set by the module.  0 retries at once")
+	authBackoff           = flag.Duration("auth_backoff", time.Minute, "Stop authenticating to a NetScaler for this long after it rejects the credentials, doubled for each further rejection, so that the account isn't locked out.  0 disables the backoff")
+	authBackoffMax        = flag.Duration("auth_backoff_max", time.Hour, "Maximum time to stop authenticating to a NetScaler after it rejects the credentials.  0 means no limit")
+	logLevel              = flag.String("log.level", "info", "Only log messages of this level or above; one of debug, info, warn or error")
 )
 
-// Exporter represents the metrics exported to Prometheus
-type Exporter struct {
-	modelID                                   *prometheus.Desc
-	mgmtCPUUsage                              *prometheus.Desc
-	memUsage                                  *prometheus.Desc
-	pktCPUUsage                               *prometheus.Desc
-	flashPartitionUsage                       *prometheus.Desc
-	varPartitionUsage                         *prometheus.Desc
-	rxMbPerSec                                *prometheus.Desc
-	txMbPerSec                                *prometheus.Desc
-	httpRequestsRate                          *prometheus.Desc
-	httpResponsesRate                         *prometheus.Desc
-	tcpCurrentClientConnections               *prometheus.Desc
-	tcpCurrentClientConnectionsEstablished    *prometheus.Desc
-	tcpCurrentServerConnections               *prometheus.Desc
-	tcpCurrentServerConnectionsEstablished    *prometheus.Desc
-	interfacesRxBytesPerSecond                *prometheus.GaugeVec
-	interfacesTxBytesPerSecond                *prometheus.GaugeVec
-	interfacesRxPacketsPerSecond              *prometheus.GaugeVec
-	interfacesTxPacketsPerSecond              *prometheus.GaugeVec
-	interfacesJumboPacketsRxPerSecond         *prometheus.GaugeVec
-	interfacesJumboPacketsTxPerSecond         *prometheus.GaugeVec
-	interfacesErrorPacketsRxPerSecond         *prometheus.GaugeVec
-	virtualServersWaitingRequests             *prometheus.GaugeVec
-	virtualServersHealth                      *prometheus.GaugeVec
-	virtualServersInactiveServices            *prometheus.GaugeVec
-	virtualServersActiveServices              *prometheus.GaugeVec
-	virtualServersTotalHits                   *prometheus.CounterVec
-	virtualServersHitsRate                    *prometheus.GaugeVec
-	virtualServersTotalRequests               *prometheus.CounterVec
-	virtualServersRequestsRate                *prometheus.GaugeVec
-	virtualServersTotalResponses              *prometheus.CounterVec
-	virtualServersReponsesRate                *prometheus.GaugeVec
-	virtualServersTotalRequestBytes           *prometheus.CounterVec
-	virtualServersRequestBytesRate            *prometheus.GaugeVec
-	virtualServersTotalResponseBytes          *prometheus.CounterVec
-	virtualServersReponseBytesRate            *prometheus.GaugeVec
-	virtualServersCurrentClientConnections    *prometheus.GaugeVec
-	virtualServersCurrentServerConnections    *prometheus.GaugeVec
-	servicesThroughput                        *prometheus.CounterVec
-	servicesThroughputRate                    *prometheus.GaugeVec
-	servicesAvgTTFB                           *prometheus.GaugeVec
-	servicesState                             *prometheus.GaugeVec
-	servicesTotalRequests                     *prometheus.CounterVec
-	servicesRequestsRate                      *prometheus.GaugeVec
-	servicesTotalResponses                    *prometheus.CounterVec
-	servicesResponsesRate                     *prometheus.GaugeVec
-	servicesTotalRequestBytes                 *prometheus.CounterVec
-	servicesRequestBytesRate                  *prometheus.GaugeVec
-	servicesTotalResponseBytes                *prometheus.CounterVec
-	servicesResponseBytesRate                 *prometheus.GaugeVec
-	servicesCurrentClientConns                *prometheus.GaugeVec
-	servicesSurgeCount                        *prometheus.GaugeVec
-	servicesCurrentServerConns                *prometheus.GaugeVec
-	servicesServerEstablishedConnections      *prometheus.GaugeVec
-	servicesCurrentReusePool                  *prometheus.GaugeVec
-	servicesMaxClients                        *prometheus.GaugeVec
-	servicesCurrentLoad                       *prometheus.GaugeVec
-	servicesVirtualServerServiceHits          *prometheus.CounterVec
-	servicesVirtualServerServiceHitsRate      *prometheus.GaugeVec
-	servicesActiveTransactions                *prometheus.GaugeVec
-	serviceGroupsState                        *prometheus.GaugeVec
-	serviceGroupsAvgTTFB                      *prometheus.GaugeVec
-	serviceGroupsTotalRequests                *prometheus.CounterVec
-	serviceGroupsRequestsRate                 *prometheus.GaugeVec
-	serviceGroupsTotalResponses               *prometheus.CounterVec
-	serviceGroupsResponsesRate                *prometheus.GaugeVec
-	serviceGroupsTotalRequestBytes            *prometheus.CounterVec
-	serviceGroupsRequestBytesRate             *prometheus.GaugeVec
-	serviceGroupsTotalResponseBytes           *prometheus.CounterVec
-	serviceGroupsResponseBytesRate            *prometheus.GaugeVec
-	serviceGroupsCurrentClientConnections     *prometheus.GaugeVec
-	serviceGroupsSurgeCount                   *prometheus.GaugeVec
-	serviceGroupsCurrentServerConnections     *prometheus.GaugeVec
-	serviceGroupsServerEstablishedConnections *prometheus.GaugeVec
-	serviceGroupsCurrentReusePool             *prometheus.GaugeVec
-	serviceGroupsMaxClients                   *prometheus.GaugeVec
-}
-
-// NewExporter initialises the exporter
-func NewExporter() (*Exporter, error) {
-	return &Exporter{
-		modelID:                                   modelID,
-		mgmtCPUUsage:                              mgmtCPUUsage,
-		memUsage:                                  memUsage,
-		pktCPUUsage:                               pktCPUUsage,
-		flashPartitionUsage:                       flashPartitionUsage,
-		varPartitionUsage:                         varPartitionUsage,
-		rxMbPerSec:                                rxMbPerSec,
-		txMbPerSec:                                txMbPerSec,
-		httpRequestsRate:                          httpRequestsRate,
-		httpResponsesRate:                         httpResponsesRate,
-		tcpCurrentClientConnections:               tcpCurrentClientConnections,
-		tcpCurrentClientConnectionsEstablished:    tcpCurrentClientConnectionsEstablished,
-		tcpCurrentServerConnections:               tcpCurrentServerConnections,
-		tcpCurrentServerConnectionsEstablished:    tcpCurrentServerConnectionsEstablished,
-		interfacesRxBytesPerSecond:                interfacesRxBytesPerSecond,
-		interfacesTxBytesPerSecond:                interfacesTxBytesPerSecond,
-		interfacesRxPacketsPerSecond:              interfacesRxPacketsPerSecond,
-		interfacesTxPacketsPerSecond:              interfacesTxPacketsPerSecond,
-		interfacesJumboPacketsRxPerSecond:         interfacesJumboPacketsRxPerSecond,
-		interfacesJumboPacketsTxPerSecond:         interfacesJumboPacketsTxPerSecond,
-		interfacesErrorPacketsRxPerSecond:         interfacesErrorPacketsRxPerSecond,
-		virtualServersWaitingRequests:             virtualServersWaitingRequests,
-		virtualServersHealth:                      virtualServersHealth,
-		virtualServersInactiveServices:            virtualServersInactiveServices,
-		virtualServersActiveServices:              virtualServersActiveServices,
-		virtualServersTotalHits:                   virtualServersTotalHits,
-		virtualServersHitsRate:                    virtualServersHitsRate,
-		virtualServersTotalRequests:               virtualServersTotalRequests,
-		virtualServersRequestsRate:                virtualServersRequestsRate,
-		virtualServersTotalResponses:              virtualServersTotalResponses,
-		virtualServersReponsesRate:                virtualServersReponsesRate,
-		virtualServersTotalRequestBytes:           virtualServersTotalRequestBytes,
-		virtualServersRequestBytesRate:            virtualServersRequestBytesRate,
-		virtualServersTotalResponseBytes:          virtualServersTotalResponseBytes,
-		virtualServersReponseBytesRate:            virtualServersReponseBytesRate,
-		virtualServersCurrentClientConnections:    virtualServersCurrentClientConnections,
-		virtualServersCurrentServerConnections:    virtualServersCurrentServerConnections,
-		servicesThroughput:                        servicesThroughput,
-		servicesThroughputRate:                    servicesThroughputRate,
-		servicesAvgTTFB:                           servicesAvgTTFB,
-		servicesState:                             servicesState,
-		servicesTotalRequests:                     servicesTotalRequests,
-		servicesRequestsRate:                      servicesRequestsRate,
-		servicesTotalResponses:                    servicesTotalResponses,
-		servicesResponsesRate:                     servicesResponsesRate,
-		servicesTotalRequestBytes:                 servicesTotalRequestBytes,
-		servicesRequestBytesRate:                  servicesRequestBytesRate,
-		servicesTotalResponseBytes:                servicesTotalResponseBytes,
-		servicesResponseBytesRate:                 servicesResponseBytesRate,
-		servicesCurrentClientConns:                servicesCurrentClientConns,
-		servicesSurgeCount:                        servicesSurgeCount,
-		servicesCurrentServerConns:                servicesCurrentServerConns,
-		servicesServerEstablishedConnections:      servicesServerEstablishedConnections,
-		servicesCurrentReusePool:                  servicesCurrentReusePool,
-		servicesMaxClients:                        servicesMaxClients,
-		servicesCurrentLoad:                       servicesCurrentLoad,
-		servicesVirtualServerServiceHits:          servicesVirtualServerServiceHits,
-		servicesVirtualServerServiceHitsRate:      servicesVirtualServerServiceHitsRate,
-		servicesActiveTransactions:                servicesActiveTransactions,
-		serviceGroupsState:                        serviceGroupsState,
-		serviceGroupsAvgTTFB:                      serviceGroupsAvgTTFB,
-		serviceGroupsTotalRequests:                serviceGroupsTotalRequests,
-		serviceGroupsRequestsRate:                 serviceGroupsRequestsRate,
-		serviceGroupsTotalResponses:               serviceGroupsTotalResponses,
-		serviceGroupsResponsesRate:                serviceGroupsResponsesRate,
-		serviceGroupsTotalRequestBytes:            serviceGroupsTotalRequestBytes,
-		serviceGroupsRequestBytesRate:             serviceGroupsRequestBytesRate,
-		serviceGroupsTotalResponseBytes:           serviceGroupsTotalResponseBytes,
-		serviceGroupsResponseBytesRate:            serviceGroupsResponseBytesRate,
-		serviceGroupsCurrentClientConnections:     serviceGroupsCurrentClientConnections,
-		serviceGroupsSurgeCount:                   serviceGroupsSurgeCount,
-		serviceGroupsCurrentServerConnections:     serviceGroupsCurrentServerConnections,
-		serviceGroupsServerEstablishedConnections: serviceGroupsServerEstablishedConnections,
-		serviceGroupsCurrentReusePool:             serviceGroupsCurrentReusePool,
-		serviceGroupsMaxClients:                   serviceGroupsMaxClients,
-	}, nil
-}
-
-// Describe implements Collector
-func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- modelID
-	ch <- mgmtCPUUsage
-	ch <- memUsage
-	ch <- pktCPUUsage
-	ch <- flashPartitionUsage
-	ch <- varPartitionUsage
-	ch <- rxMbPerSec
-	ch <- txMbPerSec
-	ch <- httpRequestsRate
-	ch <- httpResponsesRate
-	ch <- tcpCurrentClientConnections
-	ch <- tcpCurrentClientConnectionsEstablished
-	ch <- tcpCurrentServerConnections
-	ch <- tcpCurrentServerConnectionsEstablished
-
-	e.interfacesRxBytesPerSecond.Describe(ch)
-	e.interfacesTxBytesPerSecond.Describe(ch)
-	e.interfacesRxPacketsPerSecond.Describe(ch)
-	e.interfacesTxPacketsPerSecond.Describe(ch)
-	e.interfacesJumboPacketsRxPerSecond.Describe(ch)
-	e.interfacesJumboPacketsTxPerSecond.Describe(ch)
-	e.interfacesErrorPacketsRxPerSecond.Describe(ch)
-
-	e.virtualServersWaitingRequests.Describe(ch)
-	e.virtualServersHealth.Describe(ch)
-	e.virtualServersInactiveServices.Describe(ch)
-	e.virtualServersActiveServices.Describe(ch)
-	e.virtualServersTotalHits.Describe(ch)
-	e.virtualServersHitsRate.Describe(ch)
-	e.virtualServersTotalRequests.Describe(ch)
-	e.virtualServersRequestsRate.Describe(ch)
-	e.virtualServersTotalResponses.Describe(ch)
-	e.virtualServersReponsesRate.Describe(ch)
-	e.virtualServersTotalRequestBytes.Describe(ch)
-	e.virtualServersRequestBytesRate.Describe(ch)
-	e.virtualServersTotalResponseBytes.Describe(ch)
-	e.virtualServersReponseBytesRate.Describe(ch)
-	e.virtualServersCurrentClientConnections.Describe(ch)
-	e.virtualServersCurrentServerConnections.Describe(ch)
-
-	e.servicesThroughput.Describe(ch)
-	e.servicesThroughputRate.Describe(ch)
-	e.servicesAvgTTFB.Describe(ch)
-	e.servicesState.Describe(ch)
-	e.servicesTotalRequests.Describe(ch)
-	e.servicesRequestsRate.Describe(ch)
-	e.servicesTotalResponses.Describe(ch)
-	e.servicesResponsesRate.Describe(ch)
-	e.servicesTotalRequestBytes.Describe(ch)
-	e.servicesRequestBytesRate.Describe(ch)
-	e.servicesTotalResponseBytes.Describe(ch)
-	e.servicesResponseBytesRate.Describe(ch)
-	e.servicesCurrentClientConns.Describe(ch)
-	e.servicesSurgeCount.Describe(ch)
-	e.servicesCurrentServerConns.Describe(ch)
-	e.servicesServerEstablishedConnections.Describe(ch)
-	e.servicesCurrentReusePool.Describe(ch)
-	e.servicesMaxClients.Describe(ch)
-	e.servicesCurrentLoad.Describe(ch)
-	e.servicesVirtualServerServiceHits.Describe(ch)
-	e.servicesVirtualServerServiceHitsRate.Describe(ch)
-	e.servicesActiveTransactions.Describe(ch)
-
-	e.serviceGroupsState.Describe(ch)
-	e.serviceGroupsAvgTTFB.Describe(ch)
-	e.serviceGroupsTotalRequests.Describe(ch)
-	e.serviceGroupsRequestsRate.Describe(ch)
-	e.serviceGroupsTotalResponses.Describe(ch)
-	e.serviceGroupsResponsesRate.Describe(ch)
-	e.serviceGroupsTotalRequestBytes.Describe(ch)
-	e.serviceGroupsRequestBytesRate.Describe(ch)
-	e.serviceGroupsTotalResponseBytes.Describe(ch)
-	e.serviceGroupsResponseBytesRate.Describe(ch)
-	e.serviceGroupsCurrentClientConnections.Describe(ch)
-	e.serviceGroupsSurgeCount.Describe(ch)
-	e.serviceGroupsCurrentServerConnections.Describe(ch)
-	e.serviceGroupsServerEstablishedConnections.Describe(ch)
-	e.serviceGroupsCurrentReusePool.Describe(ch)
-	e.serviceGroupsMaxClients.Describe(ch)
-}
-
-func (e *Exporter) collectInterfacesRxBytesPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesRxBytesPerSecond.Reset()
-
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesRxBytesPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.ReceivedBytesPerSecond)
-	}
+// logLevels maps the accepted values of the log.level flag to the levels which are logged
+var logLevels = map[string]level.Option{
+	"debug": level.AllowDebug(),
+	"info":  level.AllowInfo(),
+	"warn":  level.AllowWarn(),
+	"error": level.AllowError(),
 }
 
-func (e *Exporter) collectInterfacesTxBytesPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesTxBytesPerSecond.Reset()
+// instanceName returns the NetScaler hostname from the management URL, for use as the ns_instance label.
+func instanceName(url string) string {
+	name := strings.TrimSpace(url)
+	name = strings.TrimPrefix(name, "https://")
+	name = strings.TrimPrefix(name, "http://")
 
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesTxBytesPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.TransmitBytesPerSecond)
-	}
+	return strings.Trim(name, " /")
 }
 
-func (e *Exporter) collectInterfacesRxPacketsPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesRxPacketsPerSecond.Reset()
-
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesRxPacketsPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.ReceivedPacketsPerSecond)
-	}
-}
-
-func (e *Exporter) collectInterfacesTxPacketsPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesTxPacketsPerSecond.Reset()
-
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesTxPacketsPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.TransmitPacketsPerSecond)
+// loadConfiguration builds the configuration from the config file, if given, and the command line flags, which override the default module.
+func loadConfiguration() (*config, error) {
+	c := &config{
+		Modules: map[string]module{},
 	}
-}
-
-func (e *Exporter) collectInterfacesJumboPacketsRxPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesJumboPacketsRxPerSecond.Reset()
-
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesJumboPacketsRxPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.JumboPacketsReceivedPerSecond)
-	}
-}
-
-func (e *Exporter) collectInterfacesJumboPacketsTxPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesJumboPacketsTxPerSecond.Reset()
-
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesJumboPacketsTxPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.JumboPacketsTransmittedPerSecond)
-	}
-}
-
-func (e *Exporter) collectInterfacesErrorPacketsRxPerSecond(ns netscaler.NSAPIResponse) {
-	e.interfacesErrorPacketsRxPerSecond.Reset()
-
-	for _, iface := range ns.InterfaceStats {
-		e.interfacesErrorPacketsRxPerSecond.WithLabelValues(nsInstance, iface.ID, iface.Alias).Set(iface.ErrorPacketsReceivedPerSecond)
-	}
-}
 
-func (e *Exporter) collectVirtualServerWaitingRequests(ns netscaler.NSAPIResponse) {
-	e.virtualServersWaitingRequests.Reset()
+	if *configFile != "" {
+		var err error
 
-	for _, vs := range ns.VirtualServerStats {
-		waitingRequests, _ := strconv.ParseFloat(vs.WaitingRequests, 64)
-		e.virtualServersWaitingRequests.WithLabelValues(nsInstance, vs.Name).Set(waitingRequests)
-	}
-}
-
-func (e *Exporter) collectVirtualServerHealth(ns netscaler.NSAPIResponse) {
-	e.virtualServersHealth.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		health, _ := strconv.ParseFloat(vs.Health, 64)
-		e.virtualServersHealth.WithLabelValues(nsInstance, vs.Name).Set(health)
-	}
-}
-
-func (e *Exporter) collectVirtualServerInactiveServices(ns netscaler.NSAPIResponse) {
-	e.virtualServersInactiveServices.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		inactiveServices, _ := strconv.ParseFloat(vs.InactiveServices, 64)
-		e.virtualServersInactiveServices.WithLabelValues(nsInstance, vs.Name).Set(inactiveServices)
-	}
-}
-
-func (e *Exporter) collectVirtualServerActiveServices(ns netscaler.NSAPIResponse) {
-	e.virtualServersActiveServices.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		activeServices, _ := strconv.ParseFloat(vs.ActiveServices, 64)
-		e.virtualServersActiveServices.WithLabelValues(nsInstance, vs.Name).Set(activeServices)
-	}
-}
-
-func (e *Exporter) collectVirtualServerTotalHits(ns netscaler.NSAPIResponse) {
-	e.virtualServersTotalHits.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		totalHits, _ := strconv.ParseFloat(vs.TotalHits, 64)
-		e.virtualServersTotalHits.WithLabelValues(nsInstance, vs.Name).Set(totalHits)
-	}
-}
-
-func (e *Exporter) collectVirtualServerHitsRate(ns netscaler.NSAPIResponse) {
-	e.virtualServersHitsRate.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		e.virtualServersHitsRate.WithLabelValues(nsInstance, vs.Name).Set(vs.HitsRate)
-	}
-}
-
-func (e *Exporter) collectVirtualServerTotalRequests(ns netscaler.NSAPIResponse) {
-	e.virtualServersTotalRequests.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		totalRequests, _ := strconv.ParseFloat(vs.TotalRequests, 64)
-		e.virtualServersTotalRequests.WithLabelValues(nsInstance, vs.Name).Set(totalRequests)
-	}
-}
-
-func (e *Exporter) collectVirtualServerRequestsRate(ns netscaler.NSAPIResponse) {
-	e.virtualServersRequestsRate.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		e.virtualServersRequestsRate.WithLabelValues(nsInstance, vs.Name).Set(vs.RequestsRate)
-	}
-}
-
-func (e *Exporter) collectVirtualServerTotalResponses(ns netscaler.NSAPIResponse) {
-	e.virtualServersTotalResponses.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		totalResponses, _ := strconv.ParseFloat(vs.TotalResponses, 64)
-		e.virtualServersTotalResponses.WithLabelValues(nsInstance, vs.Name).Set(totalResponses)
-	}
-}
-
-func (e *Exporter) collectVirtualServerResponsesRate(ns netscaler.NSAPIResponse) {
-	e.virtualServersReponsesRate.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		e.virtualServersReponsesRate.WithLabelValues(nsInstance, vs.Name).Set(vs.ResponsesRate)
-	}
-}
-
-func (e *Exporter) collectVirtualServerTotalRequestBytes(ns netscaler.NSAPIResponse) {
-	e.virtualServersTotalRequestBytes.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		totalRequestBytes, _ := strconv.ParseFloat(vs.TotalRequestBytes, 64)
-		e.virtualServersTotalRequestBytes.WithLabelValues(nsInstance, vs.Name).Set(totalRequestBytes)
-	}
-}
-
-func (e *Exporter) collectVirtualServerRequestBytesRate(ns netscaler.NSAPIResponse) {
-	e.virtualServersRequestBytesRate.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		e.virtualServersRequestBytesRate.WithLabelValues(nsInstance, vs.Name).Set(vs.RequestBytesRate)
-	}
-}
-
-func (e *Exporter) collectVirtualServerTotalResponseBytes(ns netscaler.NSAPIResponse) {
-	e.virtualServersTotalResponseBytes.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		totalResponseBytes, _ := strconv.ParseFloat(vs.TotalResponseBytes, 64)
-		e.virtualServersTotalResponseBytes.WithLabelValues(nsInstance, vs.Name).Set(totalResponseBytes)
-	}
-}
-
-func (e *Exporter) collectVirtualServerResponseBytesRate(ns netscaler.NSAPIResponse) {
-	e.virtualServersReponseBytesRate.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		e.virtualServersReponseBytesRate.WithLabelValues(nsInstance, vs.Name).Set(vs.ResponseBytesRate)
-	}
-}
-
-func (e *Exporter) collectVirtualServerCurrentClientConnections(ns netscaler.NSAPIResponse) {
-	e.virtualServersCurrentClientConnections.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		currentClientConnections, _ := strconv.ParseFloat(vs.CurrentClientConnections, 64)
-		e.virtualServersCurrentClientConnections.WithLabelValues(nsInstance, vs.Name).Set(currentClientConnections)
-	}
-}
-
-func (e *Exporter) collectVirtualServerCurrentServerConnections(ns netscaler.NSAPIResponse) {
-	e.virtualServersCurrentServerConnections.Reset()
-
-	for _, vs := range ns.VirtualServerStats {
-		currentServerConnections, _ := strconv.ParseFloat(vs.CurrentServerConnections, 64)
-		e.virtualServersCurrentServerConnections.WithLabelValues(nsInstance, vs.Name).Set(currentServerConnections)
-	}
-}
-
-func (e *Exporter) collectServicesThroughput(ns netscaler.NSAPIResponse) {
-	e.servicesThroughput.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.Throughput, 64)
-		e.servicesThroughput.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesThroughputRate(ns netscaler.NSAPIResponse) {
-	e.servicesThroughputRate.Reset()
-
-	for _, service := range ns.ServiceStats {
-		e.servicesThroughputRate.WithLabelValues(nsInstance, service.Name).Set(service.ThroughputRate)
-	}
-}
-
-func (e *Exporter) collectServicesAvgTTFB(ns netscaler.NSAPIResponse) {
-	e.servicesAvgTTFB.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.AvgTimeToFirstByte, 64)
-		e.servicesAvgTTFB.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesState(ns netscaler.NSAPIResponse) {
-	e.servicesState.Reset()
-
-	for _, service := range ns.ServiceStats {
-		state := 0.0
-
-		if service.State == "UP" {
-			state = 1.0
+		c, err = loadConfig(*configFile)
+		if err != nil {
+			return nil, err
 		}
-
-		e.servicesState.WithLabelValues(nsInstance, service.Name).Set(state)
-	}
-}
-
-func (e *Exporter) collectServicesTotalRequests(ns netscaler.NSAPIResponse) {
-	e.servicesTotalRequests.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.TotalRequests, 64)
-		e.servicesTotalRequests.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesRequestsRate(ns netscaler.NSAPIResponse) {
-	e.servicesRequestsRate.Reset()
-
-	for _, service := range ns.ServiceStats {
-		e.servicesRequestsRate.WithLabelValues(nsInstance, service.Name).Set(service.RequestsRate)
-	}
-}
-
-func (e *Exporter) collectServicesTotalResponses(ns netscaler.NSAPIResponse) {
-	e.servicesTotalResponses.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.TotalResponses, 64)
-		e.servicesTotalResponses.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesResponsesRate(ns netscaler.NSAPIResponse) {
-	e.servicesResponsesRate.Reset()
-
-	for _, service := range ns.ServiceStats {
-		e.servicesResponsesRate.WithLabelValues(nsInstance, service.Name).Set(service.ResponsesRate)
-	}
-}
-
-func (e *Exporter) collectServicesTotalRequestBytes(ns netscaler.NSAPIResponse) {
-	e.servicesTotalRequestBytes.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.TotalRequestBytes, 64)
-		e.servicesTotalRequestBytes.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesRequestBytesRate(ns netscaler.NSAPIResponse) {
-	e.servicesRequestBytesRate.Reset()
-
-	for _, service := range ns.ServiceStats {
-		e.servicesRequestBytesRate.WithLabelValues(nsInstance, service.Name).Set(service.RequestBytesRate)
-	}
-}
-
-func (e *Exporter) collectServicesTotalResponseBytes(ns netscaler.NSAPIResponse) {
-	e.servicesTotalResponseBytes.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.TotalResponseBytes, 64)
-		e.servicesTotalResponseBytes.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesResponseBytesRate(ns netscaler.NSAPIResponse) {
-	e.servicesResponseBytesRate.Reset()
-
-	for _, service := range ns.ServiceStats {
-		e.servicesResponseBytesRate.WithLabelValues(nsInstance, service.Name).Set(service.ResponseBytesRate)
-	}
-}
-
-func (e *Exporter) collectServicesCurrentClientConns(ns netscaler.NSAPIResponse) {
-	e.servicesCurrentClientConns.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.CurrentClientConnections, 64)
-		e.servicesCurrentClientConns.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesSurgeCount(ns netscaler.NSAPIResponse) {
-	e.servicesSurgeCount.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.SurgeCount, 64)
-		e.servicesSurgeCount.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesCurrentServerConns(ns netscaler.NSAPIResponse) {
-	e.servicesCurrentServerConns.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.CurrentServerConnections, 64)
-		e.servicesCurrentServerConns.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesServerEstablishedConnections(ns netscaler.NSAPIResponse) {
-	e.servicesServerEstablishedConnections.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.ServerEstablishedConnections, 64)
-		e.servicesServerEstablishedConnections.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesCurrentReusePool(ns netscaler.NSAPIResponse) {
-	e.servicesCurrentReusePool.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.CurrentReusePool, 64)
-		e.servicesCurrentReusePool.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesMaxClients(ns netscaler.NSAPIResponse) {
-	e.servicesMaxClients.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.MaxClients, 64)
-		e.servicesMaxClients.WithLabelValues(nsInstance, service.Name).Set(val)
 	}
-}
-
-func (e *Exporter) collectServicesCurrentLoad(ns netscaler.NSAPIResponse) {
-	e.servicesCurrentLoad.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.CurrentLoad, 64)
-		e.servicesCurrentLoad.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServicesVirtualServerServiceHits(ns netscaler.NSAPIResponse) {
-	e.servicesVirtualServerServiceHits.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.ServiceHits, 64)
-		e.servicesVirtualServerServiceHits.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
 
-func (e *Exporter) collectServicesVirtualServerServiceHitsRate(ns netscaler.NSAPIResponse) {
-	e.servicesVirtualServerServiceHitsRate.Reset()
+	if *username != "" || *password != "" || *passwordFile != "" {
+		m := c.Modules[defaultModule]
 
-	for _, service := range ns.ServiceStats {
-		e.servicesVirtualServerServiceHitsRate.WithLabelValues(nsInstance, service.Name).Set(service.ServiceHitsRate)
-	}
-}
-
-func (e *Exporter) collectServicesActiveTransactions(ns netscaler.NSAPIResponse) {
-	e.servicesActiveTransactions.Reset()
-
-	for _, service := range ns.ServiceStats {
-		val, _ := strconv.ParseFloat(service.ActiveTransactions, 64)
-		e.servicesActiveTransactions.WithLabelValues(nsInstance, service.Name).Set(val)
-	}
-}
-
-func (e *Exporter) collectServiceGroupsState(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsState.Reset()
-
-	for _, sg := range ns.ServiceGroupMemberStats {
-		state := 0.0
-
-		if sg.State == "UP" {
-			state = 1.0
+		if *username != "" {
+			m.Username = *username
 		}
 
-		e.serviceGroupsState.WithLabelValues(nsInstance, sgName, servername).Set(state)
-	}
-}
-
-func (e *Exporter) collectServiceGroupsAvgTTFB(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsAvgTTFB.Reset()
-
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.AvgTimeToFirstByte, 64)
-		e.serviceGroupsAvgTTFB.WithLabelValues(nsInstance, sgName, servername).Set(val)
-	}
-}
-
-func (e *Exporter) collectServiceGroupsTotalRequests(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsTotalRequests.Reset()
+		if *password != "" || *passwordFile != "" {
+			m.Password = *password
+			m.PasswordFile = *passwordFile
+			m.PasswordSecret = nil
+		}
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.TotalRequests, 64)
-		e.serviceGroupsTotalRequests.WithLabelValues(nsInstance, sgName, servername).Set(val)
+		c.Modules[defaultModule] = m
 	}
-}
 
-func (e *Exporter) collectServiceGroupsRequestsRate(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsRequestsRate.Reset()
-
-	for _, sg := range ns.ServiceGroupMemberStats {
-		e.serviceGroupsRequestsRate.WithLabelValues(nsInstance, sgName, servername).Set(sg.RequestsRate)
+	if *probeTargets != "" {
+		m := c.Modules[defaultModule]
+		m.ProbeTargets = append(m.ProbeTargets, *probeTargets)
+		c.Modules[defaultModule] = m
 	}
-}
 
-func (e *Exporter) collectServiceGroupsTotalResponses(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsTotalResponses.Reset()
-
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.TotalResponses, 64)
-		e.serviceGroupsTotalResponses.WithLabelValues(nsInstance, sgName, servername).Set(val)
+	if *url != "" {
+		if _, ok := c.findTarget(*url); !ok {
+			c.Targets = append(c.Targets, target{
+				URL:    *url,
+				Module: defaultModule,
+			})
+		}
 	}
-}
-
-func (e *Exporter) collectServiceGroupsResponsesRate(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsResponsesRate.Reset()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		e.serviceGroupsResponsesRate.WithLabelValues(nsInstance, sgName, servername).Set(sg.ResponsesRate)
+	err := c.validate()
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (e *Exporter) collectServiceGroupsTotalRequestBytes(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsTotalRequestBytes.Reset()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.TotalRequestBytes, 64)
-		e.serviceGroupsTotalRequestBytes.WithLabelValues(nsInstance, sgName, servername).Set(val)
-	}
+	return c, nil
 }
 
-func (e *Exporter) collectServiceGroupsRequestBytesRate(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsRequestBytesRate.Reset()
+// metricsHandler exports the exporter's own metrics along with every configured target, serving polled targets from their last poll.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		e.serviceGroupsRequestBytesRate.WithLabelValues(nsInstance, sgName, servername).Set(sg.RequestBytesRate)
+	gatherers := prometheus.Gatherers{
+		prometheus.DefaultGatherer,
 	}
-}
 
-func (e *Exporter) collectServiceGroupsTotalResponseBytes(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsTotalResponseBytes.Reset()
+	for _, t := range cfg.exportedTargets() {
+		if p, ok := findPoller(t.URL); ok {
+			gatherers = append(gatherers, p)
+			continue
+		}
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.TotalResponseBytes, 64)
-		e.serviceGroupsTotalResponseBytes.WithLabelValues(nsInstance, sgName, servername).Set(val)
-	}
-}
+		collectors, err := enabledCollectors(t.module(), r.URL.Query()["collect[]"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-func (e *Exporter) collectServiceGroupsResponseBytesRate(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsResponseBytesRate.Reset()
+		gatherer, err := targetGatherer(ctx, t, collectors)
+		if err != nil {
+			level.Error(logger).Log("msg", err, "target", t.URL)
+			continue
+		}
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		e.serviceGroupsResponseBytesRate.WithLabelValues(nsInstance, sgName, servername).Set(sg.ResponseBytesRate)
+		gatherers = append(gatherers, gatherer)
 	}
-}
-
-func (e *Exporter) collectServiceGroupsCurrentClientConnections(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsCurrentClientConnections.Reset()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.CurrentClientConnections, 64)
-		e.serviceGroupsCurrentClientConnections.WithLabelValues(nsInstance, sgName, servername).Set(val)
-	}
+	promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, r)
 }
 
-func (e *Exporter) collectServiceGroupsSurgeCount(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsSurgeCount.Reset()
+// logTLSSettings reports the TLS settings of a module or target, warning if certificate verification is disabled
+func logTLSSettings(t tlsConfig, keyvals ...interface{}) {
+	keyvals = append(keyvals, t.logValues()...)
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.SurgeCount, 64)
-		e.serviceGroupsSurgeCount.WithLabelValues(nsInstance, sgName, servername).Set(val)
+	if t.InsecureSkipVerify {
+		level.Warn(logger).Log(append([]interface{}{"msg", "TLS certificate verification is disabled"}, keyvals...)...)
+		return
 	}
-}
-
-func (e *Exporter) collectServiceGroupsCurrentServerConnections(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsCurrentServerConnections.Reset()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.CurrentServerConnections, 64)
-		e.serviceGroupsCurrentServerConnections.WithLabelValues(nsInstance, sgName, servername).Set(val)
-	}
+	level.Info(logger).Log(append([]interface{}{"msg", "TLS settings"}, keyvals...)...)
 }
 
-func (e *Exporter) collectServiceGroupsServerEstablishedConnections(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsServerEstablishedConnections.Reset()
+func main() {
+	flag.Parse()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.ServerEstablishedConnections, 64)
-		e.serviceGroupsServerEstablishedConnections.WithLabelValues(nsInstance, sgName, servername).Set(val)
+	if *versionFlg {
+		fmt.Println(app + " v" + version + " build " + build)
+		os.Exit(0)
 	}
-}
-
-func (e *Exporter) collectServiceGroupsCurrentReusePool(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsCurrentReusePool.Reset()
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.CurrentReusePool, 64)
-		e.serviceGroupsCurrentReusePool.WithLabelValues(nsInstance, sgName, servername).Set(val)
+	levelOption, validLevel := logLevels[*logLevel]
+	if !validLevel {
+		levelOption = level.AllowInfo()
 	}
-}
 
-func (e *Exporter) collectServiceGroupsMaxClients(ns netscaler.NSAPIResponse, sgName string, servername string) {
-	e.serviceGroupsMaxClients.Reset()
+	// The filter must be wrapped before the caller is added, or every message is logged from the filter
+	logger = level.NewFilter(log.NewLogfmtLogger(os.Stdout), levelOption)
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller, "app", app, "bind_port", *bindPort, "version", "v"+version, "build", build)
 
-	for _, sg := range ns.ServiceGroupMemberStats {
-		val, _ := strconv.ParseFloat(sg.MaxClients, 64)
-		e.serviceGroupsMaxClients.WithLabelValues(nsInstance, sgName, servername).Set(val)
-	}
-}
-
-// Collect is initiated by the Prometheus handler and gathers the metrics
-func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	nsClient, err := netscaler.NewNitroClient(*url, *username, *password)
-	if err != nil {
-		level.Error(logger).Log("msg", err)
+	if !validLevel {
+		level.Error(logger).Log("msg", "invalid log level", "log_level", *logLevel)
 		os.Exit(1)
 	}
 
-	err = netscaler.Connect(nsClient)
-	if err != nil {
-		level.Error(logger).Log("msg", err)
+	if *configFile == "" && (*username == "" || (*password == "" && *passwordFile == "")) {
+		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	nslicense, err := netscaler.GetNSLicense(nsClient, "")
-	if err != nil {
-		level.Error(logger).Log("msg", err)
-	}
+	var err error
 
-	ns, err := netscaler.GetNSStats(nsClient, "")
+	cfg, err = loadConfiguration()
 	if err != nil {
-		level.Error(logger).Log("msg", err)
-	}
-
-	interfaces, err := netscaler.GetInterfaceStats(nsClient, "")
-	if err != nil {
-		level.Error(logger).Log("msg", err)
-	}
-
-	virtualServers, err := netscaler.GetVirtualServerStats(nsClient, "")
-	if err != nil {
-		level.Error(logger).Log("msg", err)
-	}
-
-	services, err := netscaler.GetServiceStats(nsClient, "")
-	if err != nil {
-		level.Error(logger).Log("msg", err)
-	}
-
-	fltModelID, _ := strconv.ParseFloat(nslicense.NSLicense.ModelID, 64)
-
-	fltTCPCurrentClientConnections, _ := strconv.ParseFloat(ns.NSStats.TCPCurrentClientConnections, 64)
-	fltTCPCurrentClientConnectionsEstablished, _ := strconv.ParseFloat(ns.NSStats.TCPCurrentClientConnectionsEstablished, 64)
-	fltTCPCurrentServerConnections, _ := strconv.ParseFloat(ns.NSStats.TCPCurrentServerConnections, 64)
-	fltTCPCurrentServerConnectionsEstablished, _ := strconv.ParseFloat(ns.NSStats.TCPCurrentServerConnectionsEstablished, 64)
-
-	ch <- prometheus.MustNewConstMetric(
-		modelID, prometheus.GaugeValue, fltModelID, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		mgmtCPUUsage, prometheus.GaugeValue, ns.NSStats.MgmtCPUUsagePcnt, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		memUsage, prometheus.GaugeValue, ns.NSStats.MemUsagePcnt, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		pktCPUUsage, prometheus.GaugeValue, ns.NSStats.PktCPUUsagePcnt, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		flashPartitionUsage, prometheus.GaugeValue, ns.NSStats.FlashPartitionUsage, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		varPartitionUsage, prometheus.GaugeValue, ns.NSStats.VarPartitionUsage, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		rxMbPerSec, prometheus.GaugeValue, ns.NSStats.ReceivedMbPerSecond, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		txMbPerSec, prometheus.GaugeValue, ns.NSStats.TransmitMbPerSecond, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		httpRequestsRate, prometheus.GaugeValue, ns.NSStats.HTTPRequestsRate, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		httpResponsesRate, prometheus.GaugeValue, ns.NSStats.HTTPResponsesRate, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		tcpCurrentClientConnections, prometheus.GaugeValue, fltTCPCurrentClientConnections, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		tcpCurrentClientConnectionsEstablished, prometheus.GaugeValue, fltTCPCurrentClientConnectionsEstablished, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		tcpCurrentServerConnections, prometheus.GaugeValue, fltTCPCurrentServerConnections, nsInstance,
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		tcpCurrentServerConnectionsEstablished, prometheus.GaugeValue, fltTCPCurrentServerConnectionsEstablished, nsInstance,
-	)
-
-	e.collectInterfacesRxBytesPerSecond(interfaces)
-	e.interfacesRxBytesPerSecond.Collect(ch)
-
-	e.collectInterfacesTxBytesPerSecond(interfaces)
-	e.interfacesTxBytesPerSecond.Collect(ch)
-
-	e.collectInterfacesRxPacketsPerSecond(interfaces)
-	e.interfacesRxPacketsPerSecond.Collect(ch)
-
-	e.collectInterfacesTxPacketsPerSecond(interfaces)
-	e.interfacesTxPacketsPerSecond.Collect(ch)
-
-	e.collectInterfacesJumboPacketsRxPerSecond(interfaces)
-	e.interfacesJumboPacketsRxPerSecond.Collect(ch)
-
-	e.collectInterfacesJumboPacketsTxPerSecond(interfaces)
-	e.interfacesJumboPacketsTxPerSecond.Collect(ch)
-
-	e.collectInterfacesErrorPacketsRxPerSecond(interfaces)
-	e.interfacesErrorPacketsRxPerSecond.Collect(ch)
-
-	e.collectVirtualServerWaitingRequests(virtualServers)
-	e.virtualServersWaitingRequests.Collect(ch)
-
-	e.collectVirtualServerHealth(virtualServers)
-	e.virtualServersHealth.Collect(ch)
-
-	e.collectVirtualServerInactiveServices(virtualServers)
-	e.virtualServersInactiveServices.Collect(ch)
-
-	e.collectVirtualServerActiveServices(virtualServers)
-	e.virtualServersActiveServices.Collect(ch)
-
-	e.collectVirtualServerTotalHits(virtualServers)
-	e.virtualServersTotalHits.Collect(ch)
-
-	e.collectVirtualServerHitsRate(virtualServers)
-	e.virtualServersHitsRate.Collect(ch)
-
-	e.collectVirtualServerTotalRequests(virtualServers)
-	e.virtualServersTotalRequests.Collect(ch)
-
-	e.collectVirtualServerRequestsRate(virtualServers)
-	e.virtualServersRequestsRate.Collect(ch)
-
-	e.collectVirtualServerTotalResponses(virtualServers)
-	e.virtualServersTotalResponses.Collect(ch)
-
-	e.collectVirtualServerResponsesRate(virtualServers)
-	e.virtualServersReponsesRate.Collect(ch)
-
-	e.collectVirtualServerTotalRequestBytes(virtualServers)
-	e.virtualServersTotalRequestBytes.Collect(ch)
-
-	e.collectVirtualServerRequestBytesRate(virtualServers)
-	e.virtualServersRequestBytesRate.Collect(ch)
-
-	e.collectVirtualServerTotalResponseBytes(virtualServers)
-	e.virtualServersTotalResponseBytes.Collect(ch)
-
-	e.collectVirtualServerResponseBytesRate(virtualServers)
-	e.virtualServersReponseBytesRate.Collect(ch)
-
-	e.collectVirtualServerCurrentClientConnections(virtualServers)
-	e.virtualServersCurrentClientConnections.Collect(ch)
-
-	e.collectVirtualServerCurrentServerConnections(virtualServers)
-	e.virtualServersCurrentServerConnections.Collect(ch)
-
-	e.collectServicesThroughput(services)
-	e.servicesThroughput.Collect(ch)
-
-	e.collectServicesThroughputRate(services)
-	e.servicesThroughputRate.Collect(ch)
-
-	e.collectServicesAvgTTFB(services)
-	e.servicesAvgTTFB.Collect(ch)
-
-	e.collectServicesState(services)
-	e.servicesState.Collect(ch)
-
-	e.collectServicesTotalRequests(services)
-	e.servicesTotalRequests.Collect(ch)
-
-	e.collectServicesRequestsRate(services)
-	e.servicesRequestsRate.Collect(ch)
-
-	e.collectServicesTotalResponses(services)
-	e.servicesTotalResponses.Collect(ch)
-
-	e.collectServicesResponsesRate(services)
-	e.servicesResponsesRate.Collect(ch)
-
-	e.collectServicesTotalRequestBytes(services)
-	e.servicesTotalRequestBytes.Collect(ch)
-
-	e.collectServicesRequestBytesRate(services)
-	e.servicesRequestBytesRate.Collect(ch)
-
-	e.collectServicesTotalResponseBytes(services)
-	e.servicesTotalResponseBytes.Collect(ch)
-
-	e.collectServicesResponseBytesRate(services)
-	e.servicesResponseBytesRate.Collect(ch)
-
-	e.collectServicesCurrentClientConns(services)
-	e.servicesCurrentClientConns.Collect(ch)
-
-	e.collectServicesSurgeCount(services)
-	e.servicesSurgeCount.Collect(ch)
-
-	e.collectServicesCurrentServerConns(services)
-	e.servicesCurrentServerConns.Collect(ch)
-
-	e.collectServicesServerEstablishedConnections(services)
-	e.servicesServerEstablishedConnections.Collect(ch)
-
-	e.collectServicesCurrentReusePool(services)
-	e.servicesCurrentReusePool.Collect(ch)
-
-	e.collectServicesMaxClients(services)
-	e.servicesMaxClients.Collect(ch)
-
-	e.collectServicesCurrentLoad(services)
-	e.servicesCurrentLoad.Collect(ch)
-
-	e.collectServicesVirtualServerServiceHits(services)
-	e.servicesVirtualServerServiceHits.Collect(ch)
-
-	e.collectServicesVirtualServerServiceHitsRate(services)
-	e.servicesVirtualServerServiceHitsRate.Collect(ch)
-
-	e.collectServicesActiveTransactions(services)
-	e.servicesActiveTransactions.Collect(ch)
-
-	servicegroups, err := netscaler.GetServiceGroups(nsClient, "attrs=servicegroupname")
-	if err != nil {
-		level.Error(logger).Log("msg", err)
-	}
-
-	for _, sg := range servicegroups.ServiceGroups {
-		bindings, err2 := netscaler.GetServiceGroupMemberBindings(nsClient, sg.Name)
-		if err2 != nil {
-			level.Error(logger).Log("msg", err2)
-		}
-
-		for _, member := range bindings.ServiceGroupMemberBindings {
-			// NetScaler API has a bug which means it throws errors if you try to retrieve stats for a wildcard port (* in GUI, 65535 in API and CLI).
-			// Until Citrix resolve the issue we skip attempting to retrieve stats for those service groups.
-			if member.Port != 65535 {
-				port := strconv.FormatInt(member.Port, 10)
-
-				qs := "args=servicegroupname:" + sg.Name + ",servername:" + member.ServerName + ",port:" + port
-				stats, err2 := netscaler.GetServiceGroupMemberStats(nsClient, qs)
-				if err2 != nil {
-					level.Error(logger).Log("msg", err2)
-				}
-
-				e.collectServiceGroupsState(stats, sg.Name, member.ServerName)
-				e.serviceGroupsState.Collect(ch)
-
-				e.collectServiceGroupsAvgTTFB(stats, sg.Name, member.ServerName)
-				e.serviceGroupsAvgTTFB.Collect(ch)
-
-				e.collectServiceGroupsTotalRequests(stats, sg.Name, member.ServerName)
-				e.serviceGroupsTotalRequests.Collect(ch)
-
-				e.collectServiceGroupsRequestsRate(stats, sg.Name, member.ServerName)
-				e.serviceGroupsRequestsRate.Collect(ch)
-
-				e.collectServiceGroupsTotalResponses(stats, sg.Name, member.ServerName)
-				e.serviceGroupsTotalResponses.Collect(ch)
-
-				e.collectServiceGroupsResponsesRate(stats, sg.Name, member.ServerName)
-				e.serviceGroupsResponsesRate.Collect(ch)
-
-				e.collectServiceGroupsTotalRequestBytes(stats, sg.Name, member.ServerName)
-				e.serviceGroupsTotalRequestBytes.Collect(ch)
-
-				e.collectServiceGroupsRequestBytesRate(stats, sg.Name, member.ServerName)
-				e.serviceGroupsRequestBytesRate.Collect(ch)
-
-				e.collectServiceGroupsTotalResponseBytes(stats, sg.Name, member.ServerName)
-				e.serviceGroupsTotalResponseBytes.Collect(ch)
-
-				e.collectServiceGroupsResponseBytesRate(stats, sg.Name, member.ServerName)
-				e.serviceGroupsResponseBytesRate.Collect(ch)
-
-				e.collectServiceGroupsCurrentClientConnections(stats, sg.Name, member.ServerName)
-				e.serviceGroupsCurrentClientConnections.Collect(ch)
-
-				e.collectServiceGroupsSurgeCount(stats, sg.Name, member.ServerName)
-				e.serviceGroupsSurgeCount.Collect(ch)
-
-				e.collectServiceGroupsCurrentServerConnections(stats, sg.Name, member.ServerName)
-				e.serviceGroupsCurrentServerConnections.Collect(ch)
-
-				e.collectServiceGroupsServerEstablishedConnections(stats, sg.Name, member.ServerName)
-				e.serviceGroupsServerEstablishedConnections.Collect(ch)
-
-				e.collectServiceGroupsCurrentReusePool(stats, sg.Name, member.ServerName)
-				e.serviceGroupsCurrentReusePool.Collect(ch)
-
-				e.collectServiceGroupsMaxClients(stats, sg.Name, member.ServerName)
-				e.serviceGroupsMaxClients.Collect(ch)
-			}
-		}
-	}
-
-	err = netscaler.Disconnect(nsClient)
-	if err != nil {
-		level.Error(logger).Log("msg", err)
+		level.Error(logger).Log("msg", "invalid configuration", "err", err, "config_file", *configFile)
 		os.Exit(1)
 	}
-}
 
-func main() {
-	flag.Parse()
+	level.Info(logger).Log("msg", "Loaded configuration", "config_file", *configFile, "modules", len(cfg.Modules), "targets", len(cfg.Targets))
 
-	if *versionFlg {
-		fmt.Println(app + " v" + version + " build " + build)
-		os.Exit(0)
+	for name, m := range cfg.Modules {
+		logTLSSettings(m.TLS, "module", name)
 	}
 
-	if *url == "" || *username == "" || *password == "" {
-		flag.PrintDefaults()
-		os.Exit(1)
+	for _, t := range cfg.Targets {
+		if t.TLS != nil {
+			logTLSSettings(*t.TLS, "target", t.URL)
+		}
 	}
 
-	nsInstance = strings.TrimLeft(*url, "https://")
-	nsInstance = strings.Trim(nsInstance, " /")
-
-	logger = log.NewLogfmtLogger(os.Stdout)
-	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller, "app", app, "bind_port", *bindPort, "url", *url, "version", "v"+version, "build", build)
-
-	exporter, _ := NewExporter()
-	prometheus.MustRegister(exporter)
-
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 			<head><title>Citrix NetScaler Exporter</title></head>
 			<body>
 			<h1>Citrix NetScaler Exporter</h1>
 			<p><a href="/metrics">Metrics</a></p>
+			<p><a href="/probe?target=https://my-netscaler.something.x&module=default">Probe a NetScaler</a></p>
+			<p><a href="/sd">Targets discovered from Citrix ADM</a></p>
 			</body>
 			</html>`))
 	})
 
-	http.Handle("/metrics", promhttp.Handler())
+	// Sessions are kept open between scrapes, so log out of every NetScaler before exiting
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigs
+		level.Info(logger).Log("msg", "Shutting down", "signal", sig)
+		clients.logoutAll()
+		os.Exit(0)
+	}()
+
+	startPollers()
+	startDiscovery()
+
+	http.HandleFunc("/metrics", metricsHandler)
+	http.HandleFunc("/probe", probeHandler)
+	http.HandleFunc("/sd", sdHandler)
 
 	listeningPort := ":" + strconv.Itoa(*bindPort)
 	level.Info(logger).Log("msg", "Listening on port "+listeningPort)
 
-	err := http.ListenAndServe(listeningPort, nil)
+	err = http.ListenAndServe(listeningPort, nil)
 	if err != nil {
 		level.Error(logger).Log("msg", err)
 		os.Exit(1)
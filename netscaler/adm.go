@@ -0,0 +1,44 @@
+package netscaler
+
+// admInstanceHeader is the header which makes Citrix ADM proxy a Nitro request to one of its managed instances
+const admInstanceHeader = "_MPS_API_PROXY_MANAGED_INSTANCE_IP"
+
+// Instance returns a client for a NetScaler managed by the Citrix ADM this client is connected to, sharing its session
+func (c *NitroClient) Instance(ip string) *NitroClient {
+	return &NitroClient{
+		connection: c.connection,
+		instance:   ip,
+	}
+}
+
+// ManagedDevice represents an instance managed by Citrix ADM, as returned from the /config/managed_device endpoint of the ADM
+type ManagedDevice struct {
+	ID            string `json:"id"`
+	IPAddress     string `json:"ip_address"`
+	Hostname      string `json:"hostname"`
+	DisplayName   string `json:"display_name"`
+	Type          string `json:"type"`
+	InstanceState string `json:"instance_state"`
+	HAMasterState string `json:"ha_master_state"`
+	DatacenterID  string `json:"datacenter_id"`
+}
+
+// ManagedDeviceResource describes the inventory of instances managed by Citrix ADM
+var ManagedDeviceResource = Register(Resource{
+	Type:    Config,
+	Name:    "managed_device",
+	Element: ManagedDevice{},
+})
+
+// MPSDatacenter represents a site defined in Citrix ADM, as returned from the /config/mps_datacenter endpoint of the ADM
+type MPSDatacenter struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// MPSDatacenterResource describes the sites which the instances managed by Citrix ADM are placed in
+var MPSDatacenterResource = Register(Resource{
+	Type:    Config,
+	Name:    "mps_datacenter",
+	Element: MPSDatacenter{},
+})
@@ -0,0 +1,110 @@
+package netscaler
+
+import (
+	"net/http"
+	"testing"
+)
+
+// newFakeADM starts a fake Citrix ADM, which answers logins with 200 and a SESSID cookie; the caller must Close it
+func newFakeADM() *fakeNitro {
+	f := newFakeNitro("secret", `{"errorcode":0,"ns":{}}`)
+	f.loginStatus = http.StatusOK
+	f.cookieName = "SESSID"
+
+	return f
+}
+
+func TestInstancesShareADMLogin(t *testing.T) {
+	adm := newFakeADM()
+	defer adm.Close()
+
+	c, err := NewNitroClient(adm.URL, "stats", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instances := []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}
+
+	for _, ip := range instances {
+		_, err = c.Instance(ip).GetStats("ns", nil)
+		if err != nil {
+			t.Fatalf("request for %s: %s", ip, err)
+		}
+	}
+
+	if n := adm.loginCount(); n != 1 {
+		t.Errorf("logged in %d times, want 1", n)
+	}
+
+	var got []string
+
+	for _, r := range adm.received() {
+		ip := r.header.Get(admInstanceHeader)
+
+		if r.path == "/nitro/v1/config/login" {
+			if ip != "" {
+				t.Errorf("login sent %s header %q, want none", admInstanceHeader, ip)
+			}
+
+			continue
+		}
+
+		got = append(got, ip)
+	}
+
+	if len(got) != len(instances) {
+		t.Fatalf("got %d requests, want %d", len(got), len(instances))
+	}
+
+	for i := range instances {
+		if got[i] != instances[i] {
+			t.Errorf("request %d sent %s header %q, want %q", i, admInstanceHeader, got[i], instances[i])
+		}
+	}
+}
+
+func TestInstanceRenewsADMSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   int
+	}{
+		{"unauthorized", http.StatusUnauthorized, errcodeNotLoggedIn},
+		{"session expired", http.StatusBadRequest, errcodeSessionExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adm := newFakeADM()
+			defer adm.Close()
+
+			c, err := NewNitroClient(adm.URL, "stats", "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = c.Instance("10.0.0.1").GetStats("ns", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			adm.expire(tt.status, tt.code)
+
+			_, err = c.Instance("10.0.0.2").GetStats("ns", nil)
+			if err != nil {
+				t.Fatalf("request after the session expired: %s", err)
+			}
+
+			if n := adm.loginCount(); n != 2 {
+				t.Errorf("logged in %d times, want 2", n)
+			}
+
+			requests := adm.received()
+			last := requests[len(requests)-1]
+
+			if last.path == "/nitro/v1/config/login" || last.header.Get(admInstanceHeader) != "10.0.0.2" {
+				t.Errorf("last request was %s with %s header %q, want the retried request for 10.0.0.2", last.path, admInstanceHeader, last.header.Get(admInstanceHeader))
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package netscaler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WithAuthBackoff makes the client stop authenticating for a while after the NetScaler rejects its credentials, doubling up to max, or without limit if max is 0
+func WithAuthBackoff(backoff time.Duration, max time.Duration) ClientOption {
+	return func(c *NitroClient) {
+		c.authBackoff = backoff
+		c.maxAuthBackoff = max
+	}
+}
+
+// AuthBackoffError is returned instead of authenticating while the client is backing off after the NetScaler rejected its credentials
+type AuthBackoffError struct {
+	// Until is when the client will next try to authenticate
+	Until time.Time
+
+	// Failures is the number of consecutive times the credentials have been rejected
+	Failures int
+
+	// Err is the error with which the credentials were last rejected
+	Err error
+}
+
+func (e *AuthBackoffError) Error() string {
+	return fmt.Sprintf("not authenticating until %s after %d authentication failures: %s", e.Until.Format(time.RFC3339), e.Failures, e.Err)
+}
+
+// Unwrap returns the error with which the credentials were last rejected, so that IsAuthError reports the cause
+func (e *AuthBackoffError) Unwrap() error {
+	return e.Err
+}
+
+// AuthBackoff returns an *AuthBackoffError if the client is backing off after its credentials were rejected
+func (c *NitroClient) AuthBackoff(ctx context.Context) error {
+	c.authMu.Lock()
+	backoffErr := c.authBackoffError()
+	c.authMu.Unlock()
+
+	if backoffErr == nil {
+		return nil
+	}
+
+	changed, err := c.reloadCredentials(ctx)
+	if err != nil || !changed {
+		return backoffErr
+	}
+
+	// The failures still count towards the next backoff if the new credentials are rejected too
+	c.authMu.Lock()
+	c.authBackoffUntil = time.Time{}
+	c.authMu.Unlock()
+
+	return nil
+}
+
+// AuthBackoffUntil returns when the client will next try to authenticate, or the zero time if it isn't backing off
+func (c *NitroClient) AuthBackoffUntil() time.Time {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if time.Now().Before(c.authBackoffUntil) {
+		return c.authBackoffUntil
+	}
+
+	return time.Time{}
+}
+
+// AuthFailures returns the number of times the NetScaler has rejected the credentials of the client since it was created
+func (c *NitroClient) AuthFailures() uint64 {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	return c.authFailuresTotal
+}
+
+// authBackoffError returns the error describing the current backoff, or nil; the caller must hold authMu
+func (c *NitroClient) authBackoffError() *AuthBackoffError {
+	if !time.Now().Before(c.authBackoffUntil) {
+		return nil
+	}
+
+	return &AuthBackoffError{
+		Until:    c.authBackoffUntil,
+		Failures: c.authFailures,
+		Err:      c.lastAuthErr,
+	}
+}
+
+// authenticated records the outcome of a login, or of a request using header authentication, starting or extending the backoff if the credentials were rejected
+func (c *NitroClient) authenticated(err error) {
+	if err != nil && !IsAuthError(err) {
+		return
+	}
+
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if err == nil {
+		c.authFailures = 0
+		c.authBackoffUntil = time.Time{}
+
+		return
+	}
+
+	c.authFailuresTotal++
+	c.lastAuthErr = err
+
+	// Requests sent together may all be rejected, but only the first extends the backoff
+	if time.Now().Before(c.authBackoffUntil) {
+		return
+	}
+
+	c.authFailures++
+
+	if c.authBackoff <= 0 {
+		return
+	}
+
+	backoff := c.authBackoff
+	for i := 1; i < c.authFailures && backoff < math.MaxInt64/2 && (c.maxAuthBackoff <= 0 || backoff < c.maxAuthBackoff); i++ {
+		backoff *= 2
+	}
+
+	if c.maxAuthBackoff > 0 && backoff > c.maxAuthBackoff {
+		backoff = c.maxAuthBackoff
+	}
+
+	c.authBackoffUntil = time.Now().Add(backoff)
+}
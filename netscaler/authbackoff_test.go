@@ -0,0 +1,116 @@
+package netscaler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestHeaderAuthBacksOffAfterRejection(t *testing.T) {
+	ns := newFakeNitro("secret", `{"errorcode":0,"ns":{}}`)
+	defer ns.Close()
+
+	creds := &rotatingCredentials{passwords: []string{"wrong"}}
+
+	c, err := NewNitroClient(ns.URL, "", "", WithHeaderAuth(), WithCredentials(creds.credentials), WithAuthBackoff(time.Minute, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetStats("ns", nil)
+	if !IsAuthError(err) {
+		t.Fatalf("got error %v, want an authentication error", err)
+	}
+
+	// The unchanged credentials aren't retried
+	if n := len(ns.received()); n != 1 {
+		t.Fatalf("got %d requests, want 1", n)
+	}
+
+	var backoffErr *AuthBackoffError
+
+	if err := c.AuthBackoff(context.Background()); !errors.As(err, &backoffErr) {
+		t.Fatalf("AuthBackoff returned %v, want an *AuthBackoffError", err)
+	}
+
+	_, err = c.GetStats("ns", nil)
+	if !errors.As(err, &backoffErr) {
+		t.Fatalf("got error %v while backing off, want an *AuthBackoffError", err)
+	}
+
+	if n := len(ns.received()); n != 1 {
+		t.Errorf("got %d requests while backing off, want none", n-1)
+	}
+
+	if backoffErr.Failures != 1 || time.Until(backoffErr.Until) <= 0 {
+		t.Errorf("backing off until %s after %d failures, want a minute after 1", backoffErr.Until, backoffErr.Failures)
+	}
+
+	if n := c.AuthFailures(); n != 1 {
+		t.Errorf("AuthFailures returned %d, want 1", n)
+	}
+}
+
+func TestAuthBackoffGrowth(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  time.Duration
+		max      time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{"first rejection", time.Minute, time.Hour, 1, time.Minute},
+		{"doubled", time.Minute, time.Hour, 3, 4 * time.Minute},
+		{"capped", time.Minute, time.Hour, 10, time.Hour},
+		{"max below backoff", time.Minute, time.Second, 1, time.Second},
+		{"no max", time.Minute, 0, 10, 512 * time.Minute},
+		{"no max without overflow", time.Minute, 0, 100, (1 << 27) * time.Minute},
+		{"disabled", 0, time.Hour, 3, 0},
+	}
+
+	rejected := &NitroError{StatusCode: http.StatusUnauthorized}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewNitroClient("http://netscaler.invalid", "", "", WithAuthBackoff(tt.backoff, tt.max))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var until time.Time
+
+			for i := 0; i < tt.failures; i++ {
+				// Let the previous backoff expire, so that the rejection extends it
+				c.authBackoffUntil = time.Time{}
+
+				start := time.Now()
+				c.authenticated(rejected)
+				until = c.authBackoffUntil
+
+				if tt.want == 0 {
+					if !until.IsZero() {
+						t.Fatalf("backing off until %s, want no backoff", until)
+					}
+
+					continue
+				}
+
+				if i == tt.failures-1 {
+					got := until.Sub(start)
+					if got < tt.want || got > tt.want+time.Second {
+						t.Errorf("backing off for %s after %d failures, want %s", got, tt.failures, tt.want)
+					}
+				}
+			}
+
+			c.authenticated(nil)
+
+			if c.authFailures != 0 || !c.authBackoffUntil.IsZero() {
+				t.Errorf("backoff not reset by a successful authentication")
+			}
+		})
+	}
+}
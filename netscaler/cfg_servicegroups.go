@@ -1,29 +1,15 @@
 package netscaler
 
-import (
-	"encoding/json"
-
-	"github.com/pkg/errors"
-)
-
-// ServiceGroups represents the data returned from the /config/servicegroup Nitro API endpoint
+// ServiceGroups represents the data returned from the /config/servicegroup and /stat/servicegroup Nitro API endpoints
 type ServiceGroups struct {
-	Name string `json:"servicegroupname"`
+	Name    string                    `json:"servicegroupname"`
+	State   string                    `json:"state"`
+	Members []ServiceGroupMemberStats `json:"servicegroupmember"`
 }
 
-// GetServiceGroups queries the Nitro API for service group config
-func GetServiceGroups(c *NitroClient, querystring string) (NSAPIResponse, error) {
-	cfg, err := c.GetConfig("servicegroup", querystring)
-	if err != nil {
-		return NSAPIResponse{}, err
-	}
-
-	var response = new(NSAPIResponse)
-
-	err = json.Unmarshal(cfg, &response)
-	if err != nil {
-		return NSAPIResponse{}, errors.Wrap(err, "error unmarshalling response body")
-	}
-
-	return *response, nil
-}
+// ServiceGroupsResource describes the configuration of each service group
+var ServiceGroupsResource = Register(Resource{
+	Type:    Config,
+	Name:    "servicegroup",
+	Element: ServiceGroups{},
+})
@@ -1,9 +1,14 @@
 package netscaler
 
 import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -11,31 +16,341 @@ import (
 
 // NitroClient represents the client used to connect to the API
 type NitroClient struct {
-	url      string
-	username string
-	password string
-	client   *http.Client
+	*connection
+
+	// instance is the IP address of the managed instance to which Citrix ADM proxies the requests of the client, if it is connected to an ADM
+	instance string
+}
+
+// connection holds the session, connections and options of a client, which are shared by the clients of every instance managed by an ADM
+type connection struct {
+	url        string
+	username   string
+	password   string
+	tlsConfig  *tls.Config
+	headerAuth bool
+	client     *http.Client
+	requests   chan struct{}
+	pageSize   int
+
+	maxResponseSize int64
+
+	credentials CredentialsFunc
+	credsMu     sync.RWMutex
+	credsLoaded bool
+
+	authBackoff       time.Duration
+	maxAuthBackoff    time.Duration
+	authMu            sync.Mutex
+	authFailures      int
+	authFailuresTotal uint64
+	authBackoffUntil  time.Time
+	lastAuthErr       error
+
+	maxRetries   int
+	retryBackoff time.Duration
+	onRetry      func(err error)
+	onRequest    func(RequestInfo)
+
+	mu       sync.Mutex
+	loggedIn bool
+	session  uint64
+}
+
+// ClientOption configures optional behaviour of a NitroClient
+type ClientOption func(*NitroClient)
+
+// WithTLSConfig sets the TLS configuration used when connecting to the NetScaler management interface
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *NitroClient) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithHeaderAuth makes the client send its credentials in the headers of every request rather than logging in
+func WithHeaderAuth() ClientOption {
+	return func(c *NitroClient) {
+		c.headerAuth = true
+	}
+}
+
+// WithMaxConcurrentRequests limits the number of requests the client sends to the NetScaler at the same time
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *NitroClient) {
+		if n > 0 {
+			c.requests = make(chan struct{}, n)
+		} else {
+			c.requests = nil
+		}
+	}
+}
+
+// WithMaxResponseSize limits the size of the responses the client reads
+func WithMaxResponseSize(n int64) ClientOption {
+	return func(c *NitroClient) {
+		c.maxResponseSize = n
+	}
 }
 
 // NewNitroClient creates a new client used to interact with the Nirto API.
 // URL, username and password are passed to this function to allow connections to any NetScaler endpoint.
-func NewNitroClient(url string, username string, password string) (*NitroClient, error) {
-	c := new(NitroClient)
+func NewNitroClient(url string, username string, password string, opts ...ClientOption) (*NitroClient, error) {
+	c := &NitroClient{
+		connection: &connection{},
+	}
 
 	c.url = strings.Trim(url, " /") + "/nitro/v1/"
 
 	c.username = username
 	c.password = password
 
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return c, errors.Wrap(err, "error creating cookiejar")
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.tlsConfig != nil {
+		transport.TLSClientConfig = c.tlsConfig
+	}
+
+	// Requests are bounded by their context rather than a fixed timeout, so that slow NetScalers can be given longer
 	c.client = &http.Client{
-		Timeout: 10 * time.Second,
-		Jar:     jar,
+		Jar:       jar,
+		Transport: transport,
 	}
 
 	return c, nil
 }
+
+// ensureSession logs in if the client has no session, and returns the ID of the current session
+func (c *NitroClient) ensureSession(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.headerAuth {
+		err := c.AuthBackoff(ctx)
+		if err != nil {
+			return c.session, err
+		}
+
+		return c.session, c.loadCredentials(ctx)
+	}
+
+	if c.loggedIn {
+		return c.session, nil
+	}
+
+	err := connect(ctx, c)
+	if err != nil {
+		return c.session, err
+	}
+
+	return c.session, nil
+}
+
+// renewSession logs in again after the given session has expired, unless another request already has
+func (c *NitroClient) renewSession(ctx context.Context, expired uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loggedIn && c.session != expired {
+		return nil
+	}
+
+	c.loggedIn = false
+
+	return connect(ctx, c)
+}
+
+// get sends a GET request for the given Nitro path and returns the body of the response
+func (c *NitroClient) get(ctx context.Context, path string, querystring string) ([]byte, error) {
+	body, err := c.open(ctx, path, querystring)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading response body")
+	}
+
+	return b, nil
+}
+
+// open sends a GET request for the given Nitro path, renewing the session if it has expired; the caller must close the body
+func (c *NitroClient) open(ctx context.Context, path string, querystring string) (io.ReadCloser, error) {
+	url := c.url + path
+
+	if querystring != "" {
+		url = url + "?" + querystring
+	}
+
+	session, err := c.ensureSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.request(ctx, url)
+	if c.headerAuth {
+		body, err = c.retryHeaderAuth(ctx, url, body, err)
+	}
+
+	if err == nil {
+		return body, nil
+	}
+
+	if !IsAuthError(err) || c.headerAuth {
+		return nil, errors.Wrap(err, "read failed")
+	}
+
+	err = c.renewSession(ctx, session)
+	if err != nil {
+		return nil, errors.Wrap(err, "error renewing expired session")
+	}
+
+	body, err = c.request(ctx, url)
+	if err != nil {
+		return nil, errors.Wrap(err, "read failed")
+	}
+
+	return body, nil
+}
+
+// retryHeaderAuth retries a request using header authentication once if its credentials were rejected and have changed
+func (c *NitroClient) retryHeaderAuth(ctx context.Context, url string, body io.ReadCloser, err error) (io.ReadCloser, error) {
+	if IsAuthError(err) {
+		changed, reloadErr := c.reloadCredentials(ctx)
+		if reloadErr == nil && changed {
+			body, err = c.request(ctx, url)
+		}
+	}
+
+	c.authenticated(err)
+
+	return body, err
+}
+
+// request sends a GET request, retrying transient failures, and returns the body of a successful response
+func (c *NitroClient) request(ctx context.Context, url string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+
+	err := c.retry(ctx, func() error {
+		resp, err := c.send(ctx, url)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return readError(resp)
+		}
+
+		body = resp.Body
+
+		return nil
+	})
+
+	return body, err
+}
+
+// readError reads and closes the body of a failed response, and returns the error it describes
+func readError(resp *http.Response) *NitroError {
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	return newNitroError(resp.StatusCode, body)
+}
+
+// send performs a single GET request, holding a request slot until the body of the response is closed
+func (c *NitroClient) send(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating HTTP request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if c.instance != "" {
+		req.Header.Set(admInstanceHeader, c.instance)
+	}
+
+	if c.headerAuth {
+		username, password := c.currentCredentials()
+
+		req.Header.Set("X-NITRO-USER", username)
+		req.Header.Set("X-NITRO-PASS", password)
+	}
+
+	release := func() {}
+
+	if c.requests != nil {
+		select {
+		case c.requests <- struct{}{}:
+			release = func() { <-c.requests }
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "error waiting to send request")
+		}
+	}
+
+	resp, err := c.do(req, release)
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending request")
+	}
+
+	return resp, nil
+}
+
+// ErrResponseTooLarge is returned when reading a response larger than the maximum response size of the client
+var ErrResponseTooLarge = errors.New("response exceeds the maximum response size")
+
+// responseBody limits the size of a response body, counts the bytes read from it, and releases the request slot of its request when closed
+type responseBody struct {
+	body    io.ReadCloser
+	max     int64
+	read    int64
+	release func()
+	once    sync.Once
+}
+
+// Read returns no more than the maximum response size, so that the response is always seen to be incomplete if it is too large
+func (b *responseBody) Read(p []byte) (int, error) {
+	if b.max <= 0 {
+		n, err := b.body.Read(p)
+		b.read += int64(n)
+
+		return n, err
+	}
+
+	if b.read >= b.max {
+		// Any more data means the response is too large
+		var probe [1]byte
+
+		n, err := b.body.Read(probe[:])
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+
+		return 0, err
+	}
+
+	if remaining := b.max - b.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	b.once.Do(b.release)
+
+	return b.body.Close()
+}
@@ -2,13 +2,18 @@ package netscaler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+// sessionTimeout limits logins and logouts, which may be sent outside of a scrape with a deadline
+const sessionTimeout = 10 * time.Second
+
 // LoginCreds contains the username and password
 type LoginCreds struct {
 	Username string `json:"username"`
@@ -20,52 +25,112 @@ type LoginPayload struct {
 	Login LoginCreds `json:"login"`
 }
 
-// Connect initiates a connection to a NetScaler and returns the session token
+// Connect initiates a connection to a NetScaler and stores the session token in the client
 func Connect(c *NitroClient) error {
-	url := c.url + "config/login"
+	return ConnectContext(context.Background(), c)
+}
 
-	var p LoginPayload
+// ConnectContext is like Connect, but the login is cancelled when the context is done
+func ConnectContext(ctx context.Context, c *NitroClient) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	p.Login = LoginCreds{
-		Username: c.username,
-		Password: c.password,
+	if c.headerAuth {
+		return nil
 	}
 
-	reqBody, err := json.Marshal(p)
+	return connect(ctx, c)
+}
+
+// connect logs in to the NetScaler; the caller must hold the session lock of the client
+func connect(ctx context.Context, c *NitroClient) error {
+	ctx, cancel := context.WithTimeout(ctx, sessionTimeout)
+	defer cancel()
+
+	err := c.AuthBackoff(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error marshalling payload")
+		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	err = c.loadCredentials(ctx)
 	if err != nil {
-		return errors.Wrap(err, "error creating HTTP request")
+		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	err = login(ctx, c)
 
-	resp, err := c.client.Do(req)
-	if resp != nil {
-		defer resp.Body.Close()
+	if IsAuthError(err) {
+		changed, reloadErr := c.reloadCredentials(ctx)
+		if reloadErr == nil && changed {
+			err = login(ctx, c)
+		}
+	}
+
+	c.authenticated(err)
+
+	return err
+}
+
+// login sends the credentials of the client to the NetScaler to start a session
+func login(ctx context.Context, c *NitroClient) error {
+	url := c.url + "config/login"
+
+	username, password := c.currentCredentials()
+
+	var p LoginPayload
+
+	p.Login = LoginCreds{
+		Username: username,
+		Password: password,
 	}
+
+	reqBody, err := json.Marshal(p)
 	if err != nil {
-		return errors.Wrap(err, "error sending request")
+		return errors.Wrap(err, "error marshalling payload")
 	}
 
-	switch resp.StatusCode {
-	case 201:
-		var response = new(NSAPIResponse)
+	var resp *http.Response
 
-		body, _ := ioutil.ReadAll(resp.Body)
+	err = c.retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+		if err != nil {
+			return errors.Wrap(err, "error creating HTTP request")
+		}
+
+		req.Header.Set("Content-Type", "application/json")
 
-		err = json.Unmarshal(body, &response)
+		resp, err = c.do(req, func() {})
 		if err != nil {
-			return errors.Wrap(err, "error unmarshalling response body")
+			return errors.Wrap(err, "error sending request")
 		}
 
-		return nil
-	default:
+		// Citrix ADM answers a successful login with 200 rather than 201
+		if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
+			return nil
+		}
+
+		defer resp.Body.Close()
+
 		body, _ := ioutil.ReadAll(resp.Body)
 
-		return errors.New("read failed: " + resp.Status + " (" + string(body) + ")")
+		return errors.Wrap(newNitroError(resp.StatusCode, body), "login failed")
+	})
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
+
+	var response = new(NSAPIResponse)
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return errors.Wrap(err, "error unmarshalling response body")
+	}
+
+	c.loggedIn = true
+	c.session++
+
+	return nil
 }
@@ -0,0 +1,67 @@
+package netscaler
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// CredentialsFunc returns the username and password with which the client authenticates to the NetScaler
+type CredentialsFunc func(ctx context.Context) (username string, password string, err error)
+
+// WithCredentials makes the client read its username and password from f, again whenever they are rejected
+func WithCredentials(f CredentialsFunc) ClientOption {
+	return func(c *NitroClient) {
+		c.credentials = f
+	}
+}
+
+// loadCredentials reads the credentials from the credentials function of the client, if it has one and they haven't been read yet
+func (c *NitroClient) loadCredentials(ctx context.Context) error {
+	if c.credentials == nil {
+		return nil
+	}
+
+	c.credsMu.RLock()
+	loaded := c.credsLoaded
+	c.credsMu.RUnlock()
+
+	if loaded {
+		return nil
+	}
+
+	_, err := c.reloadCredentials(ctx)
+
+	return err
+}
+
+// reloadCredentials reads the credentials from the credentials function of the client again, and reports whether they have changed
+func (c *NitroClient) reloadCredentials(ctx context.Context) (bool, error) {
+	if c.credentials == nil {
+		return false, nil
+	}
+
+	username, password, err := c.credentials(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "error reading credentials")
+	}
+
+	c.credsMu.Lock()
+	defer c.credsMu.Unlock()
+
+	changed := !c.credsLoaded || username != c.username || password != c.password
+
+	c.username = username
+	c.password = password
+	c.credsLoaded = true
+
+	return changed, nil
+}
+
+// currentCredentials returns the username and password the client authenticates with
+func (c *NitroClient) currentCredentials() (string, string) {
+	c.credsMu.RLock()
+	defer c.credsMu.RUnlock()
+
+	return c.username, c.password
+}
@@ -0,0 +1,114 @@
+package netscaler
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// rotatingCredentials returns each password in turn, and then the last one, counting the times it is called
+type rotatingCredentials struct {
+	mu        sync.Mutex
+	passwords []string
+	calls     int
+}
+
+func (r *rotatingCredentials) credentials(ctx context.Context) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.calls
+	if i >= len(r.passwords) {
+		i = len(r.passwords) - 1
+	}
+
+	r.calls++
+
+	return "stats", r.passwords[i], nil
+}
+
+func TestLoginRetriesWithChangedCredentials(t *testing.T) {
+	ns := newFakeNitro("rotated", `{"errorcode":0,"ns":{}}`)
+	defer ns.Close()
+
+	creds := &rotatingCredentials{passwords: []string{"old", "rotated"}}
+
+	c, err := NewNitroClient(ns.URL, "", "", WithCredentials(creds.credentials))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetStats("ns", nil)
+	if err != nil {
+		t.Fatalf("request with rotated password: %s", err)
+	}
+
+	if n := ns.loginCount(); n != 1 {
+		t.Errorf("logged in %d times, want 1", n)
+	}
+
+	if creds.calls != 2 {
+		t.Errorf("read credentials %d times, want 2", creds.calls)
+	}
+}
+
+func TestLoginNotRetriedWithUnchangedCredentials(t *testing.T) {
+	ns := newFakeNitro("secret", `{"errorcode":0,"ns":{}}`)
+	defer ns.Close()
+
+	creds := &rotatingCredentials{passwords: []string{"wrong"}}
+
+	c, err := NewNitroClient(ns.URL, "", "", WithCredentials(creds.credentials))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetStats("ns", nil)
+	if !IsAuthError(err) {
+		t.Fatalf("got error %v, want an authentication error", err)
+	}
+
+	logins := 0
+
+	for _, r := range ns.received() {
+		if r.path == "/nitro/v1/config/login" {
+			logins++
+		}
+	}
+
+	if logins != 1 {
+		t.Errorf("sent %d logins, want 1", logins)
+	}
+}
+
+func TestHeaderAuthRetriesWithChangedCredentials(t *testing.T) {
+	ns := newFakeNitro("rotated", `{"errorcode":0,"ns":{}}`)
+	defer ns.Close()
+
+	creds := &rotatingCredentials{passwords: []string{"old", "rotated"}}
+
+	c, err := NewNitroClient(ns.URL, "", "", WithHeaderAuth(), WithCredentials(creds.credentials))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetStats("ns", nil)
+	if err != nil {
+		t.Fatalf("request with rotated password: %s", err)
+	}
+
+	requests := ns.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+
+	for i, want := range []string{"old", "rotated"} {
+		if got := requests[i].header.Get("X-NITRO-PASS"); got != want {
+			t.Errorf("request %d sent X-NITRO-PASS %q, want %q", i, got, want)
+		}
+	}
+
+	if creds.calls != 2 {
+		t.Errorf("read credentials %d times, want 2", creds.calls)
+	}
+}
@@ -2,6 +2,7 @@ package netscaler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -17,6 +18,21 @@ type DisconnectPayload struct {
 
 // Disconnect logs out of the NetScaler
 func Disconnect(c *NitroClient) error {
+	return DisconnectContext(context.Background(), c)
+}
+
+// DisconnectContext is like Disconnect, but the logout is cancelled when the context is done
+func DisconnectContext(ctx context.Context, c *NitroClient) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loggedIn {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sessionTimeout)
+	defer cancel()
+
 	url := c.url + "config/logout"
 
 	var p DisconnectPayload
@@ -26,14 +42,14 @@ func Disconnect(c *NitroClient) error {
 		return errors.Wrap(err, "error marshalling payload")
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return errors.Wrap(err, "error creating HTTP request")
 	}
 
 	req.Header.Set("Content-Type", "application/vnd.com.citrix.netscaler.logout+json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req, func() {})
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -41,6 +57,8 @@ func Disconnect(c *NitroClient) error {
 		return errors.Wrap(err, "error sending request")
 	}
 
+	c.loggedIn = false
+
 	switch resp.StatusCode {
 	case 200:
 		return nil
@@ -50,6 +68,6 @@ func Disconnect(c *NitroClient) error {
 	default:
 		body, _ := ioutil.ReadAll(resp.Body)
 
-		return errors.New("Logout failed: " + resp.Status + " (" + string(body) + ")")
+		return errors.Wrap(newNitroError(resp.StatusCode, body), "logout failed")
 	}
 }
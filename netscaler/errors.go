@@ -0,0 +1,95 @@
+package netscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Nitro error codes which are checked by the client and the Is* helpers
+const (
+	errcodeNoSuchResource = 258
+	errcodeNotLoggedIn    = 354
+	errcodeSessionExpired = 444
+)
+
+// NitroError is returned when the NetScaler rejects a request
+type NitroError struct {
+	StatusCode int
+	Errorcode  int64
+	Message    string
+	Severity   string
+}
+
+// newNitroError builds the error for a failed response from its status code and body
+func newNitroError(status int, body []byte) *NitroError {
+	e := &NitroError{
+		StatusCode: status,
+	}
+
+	var response NSAPIResponse
+
+	if json.Unmarshal(body, &response) == nil {
+		e.Errorcode = response.Errorcode
+		e.Message = response.Message
+		e.Severity = response.Severity
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	return e
+}
+
+func (e *NitroError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+
+	if e.Errorcode != 0 {
+		msg = fmt.Sprintf("%s: errorcode %d", msg, e.Errorcode)
+
+		if e.Severity != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, e.Severity)
+		}
+	}
+
+	if e.Message != "" {
+		msg = msg + ": " + e.Message
+	}
+
+	return msg
+}
+
+// IsAuthError reports whether the error was caused by the NetScaler rejecting the credentials or the session
+func IsAuthError(err error) bool {
+	var e *NitroError
+
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.StatusCode == http.StatusUnauthorized || e.Errorcode == errcodeNotLoggedIn || e.Errorcode == errcodeSessionExpired
+}
+
+// IsNotFound reports whether the error was caused by requesting a resource which doesn't exist on the NetScaler
+func IsNotFound(err error) bool {
+	var e *NitroError
+
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.StatusCode == http.StatusNotFound || e.Errorcode == errcodeNoSuchResource
+}
+
+// IsPermissionDenied reports whether the error was caused by the user not being permitted to make the request, such as by its command policy
+func IsPermissionDenied(err error) bool {
+	var e *NitroError
+
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,137 @@
+package netscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+// fakeRequest is a request received by the fake Nitro API
+type fakeRequest struct {
+	method string
+	path   string
+	header http.Header
+}
+
+// fakeNitro is a Nitro API, or a Citrix ADM, served by httptest which records the requests it receives.  It accepts logins and header authentication with the given password, and answers every other GET with body.
+type fakeNitro struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	password    string
+	loginStatus int
+	cookieName  string
+	body        string
+	session     int
+	logins      int
+	requests    []fakeRequest
+
+	// rejectStatus and rejectCode are the HTTP status and Nitro errorcode of the response to a request whose session has expired
+	rejectStatus int
+	rejectCode   int
+}
+
+// newFakeNitro starts a fake Nitro API which behaves as a NetScaler; the caller must Close it
+func newFakeNitro(password string, body string) *fakeNitro {
+	f := &fakeNitro{
+		password:     password,
+		loginStatus:  http.StatusCreated,
+		cookieName:   "NITRO_AUTH_TOKEN",
+		body:         body,
+		rejectStatus: http.StatusUnauthorized,
+		rejectCode:   errcodeNotLoggedIn,
+	}
+
+	f.Server = httptest.NewServer(f)
+
+	return f
+}
+
+func (f *fakeNitro) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, fakeRequest{
+		method: r.Method,
+		path:   r.URL.Path,
+		header: r.Header.Clone(),
+	})
+
+	switch r.URL.Path {
+	case "/nitro/v1/config/login":
+		var p LoginPayload
+
+		err := json.NewDecoder(r.Body).Decode(&p)
+		if err != nil || p.Login.Password != f.password {
+			writeNitroError(w, http.StatusUnauthorized, errcodeNotLoggedIn)
+			return
+		}
+
+		f.logins++
+		f.session++
+
+		http.SetCookie(w, &http.Cookie{Name: f.cookieName, Value: strconv.Itoa(f.session), Path: "/"})
+		w.WriteHeader(f.loginStatus)
+		fmt.Fprintf(w, `{"errorcode":0,"message":"Done","sessionid":"%d"}`, f.session)
+
+		return
+	case "/nitro/v1/config/logout":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"errorcode":0}`))
+
+		return
+	}
+
+	if !f.authenticated(r) {
+		writeNitroError(w, f.rejectStatus, f.rejectCode)
+		return
+	}
+
+	w.Write([]byte(f.body))
+}
+
+// authenticated reports whether a request carries the current session, or the password in its headers.  The caller must hold mu.
+func (f *fakeNitro) authenticated(r *http.Request) bool {
+	if user := r.Header.Get("X-NITRO-USER"); user != "" {
+		return r.Header.Get("X-NITRO-PASS") == f.password
+	}
+
+	cookie, err := r.Cookie(f.cookieName)
+
+	return err == nil && f.session > 0 && cookie.Value == strconv.Itoa(f.session)
+}
+
+// expire ends the current session, so that the next request is rejected with the given status and errorcode
+func (f *fakeNitro) expire(status int, code int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.session++
+	f.rejectStatus = status
+	f.rejectCode = code
+}
+
+// received returns the requests received so far
+func (f *fakeNitro) received() []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]fakeRequest{}, f.requests...)
+}
+
+// loginCount returns the number of successful logins
+func (f *fakeNitro) loginCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.logins
+}
+
+func writeNitroError(w http.ResponseWriter, status int, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errorcode":%d,"message":"Not logged in","severity":"ERROR"}`, code)
+}
@@ -0,0 +1,66 @@
+package netscaler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderAuthSendsCredentials(t *testing.T) {
+	ns := newFakeNitro("secret", `{"errorcode":0,"ns":{}}`)
+	defer ns.Close()
+
+	c, err := NewNitroClient(ns.URL, "stats", "secret", WithHeaderAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = c.GetStats("ns", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests := ns.received()
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+
+	for i, r := range requests {
+		if r.path == "/nitro/v1/config/login" {
+			t.Errorf("request %d logged in", i)
+		}
+
+		if user, pass := r.header.Get("X-NITRO-USER"), r.header.Get("X-NITRO-PASS"); user != "stats" || pass != "secret" {
+			t.Errorf("request %d sent X-NITRO-USER %q and X-NITRO-PASS %q, want stats and secret", i, user, pass)
+		}
+
+		if _, err := (&http.Request{Header: r.header}).Cookie("NITRO_AUTH_TOKEN"); err == nil {
+			t.Errorf("request %d sent a session cookie", i)
+		}
+	}
+
+	if n := ns.loginCount(); n != 0 {
+		t.Errorf("logged in %d times, want none", n)
+	}
+}
+
+func TestHeaderAuthRejectedPassword(t *testing.T) {
+	ns := newFakeNitro("secret", `{"errorcode":0,"ns":{}}`)
+	defer ns.Close()
+
+	c, err := NewNitroClient(ns.URL, "stats", "wrong", WithHeaderAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetStats("ns", nil)
+	if !IsAuthError(err) {
+		t.Fatalf("got error %v, want an authentication error", err)
+	}
+
+	// There is no session to renew, so the request isn't sent again and no login is tried
+	if n := len(ns.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
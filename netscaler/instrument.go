@@ -0,0 +1,102 @@
+package netscaler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes a request sent to the Nitro API, once its response has been read
+type RequestInfo struct {
+	// Method is the HTTP method of the request
+	Method string
+
+	// Endpoint is the Nitro path of the request without the names of resources, such as stat/lbvserver or config/login
+	Endpoint string
+
+	// Path is the full Nitro path of the request, without the query string
+	Path string
+
+	// Instance is the IP address of the managed instance to which Citrix ADM proxied the request, if it was sent to an ADM
+	Instance string
+
+	// StatusCode is the HTTP status of the response, or 0 if no response was received
+	StatusCode int
+
+	// Duration is the time from sending the request until the response had been read, or the request failed
+	Duration time.Duration
+
+	// Bytes is the size of the body of the response which was read
+	Bytes int64
+
+	// Err is the error if no response was received
+	Err error
+}
+
+// WithRequestHook sets a function which is called for every request sent to the Nitro API
+func WithRequestHook(f func(RequestInfo)) ClientOption {
+	return func(c *NitroClient) {
+		c.onRequest = f
+	}
+}
+
+// do sends a request, and arranges for the request hook of the client to be called when the body of the response is closed
+func (c *NitroClient) do(req *http.Request, release func()) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/nitro/v1/")
+
+	info := RequestInfo{
+		Method:   req.Method,
+		Endpoint: endpoint(path),
+		Path:     path,
+		Instance: c.instance,
+	}
+
+	start := time.Now()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		release()
+
+		info.Duration = time.Since(start)
+		info.Err = err
+		c.observe(info)
+
+		return nil, err
+	}
+
+	info.StatusCode = resp.StatusCode
+
+	body := &responseBody{
+		body: resp.Body,
+		max:  c.maxResponseSize,
+	}
+
+	body.release = func() {
+		release()
+
+		info.Duration = time.Since(start)
+		info.Bytes = body.read
+		c.observe(info)
+	}
+
+	resp.Body = body
+
+	return resp, nil
+}
+
+// observe calls the request hook of the client, if it has one
+func (c *NitroClient) observe(info RequestInfo) {
+	if c.onRequest != nil {
+		c.onRequest(info)
+	}
+}
+
+// endpoint normalises a Nitro path to its first two segments, the API and the resource type, so that requests for named resources are grouped together
+func endpoint(path string) string {
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,69 @@
+package netscaler
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// Number is a numeric value from a Nitro response, which may be a string, a number, or missing
+type Number struct {
+	value   float64
+	raw     string
+	present bool
+	valid   bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *Number) UnmarshalJSON(data []byte) error {
+	*n = Number{}
+
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	n.present = true
+	n.raw = string(data)
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return nil
+		}
+
+		n.raw = s
+	}
+
+	v, err := strconv.ParseFloat(n.raw, 64)
+	if err != nil {
+		return nil
+	}
+
+	n.value = v
+	n.valid = true
+
+	return nil
+}
+
+// Float64 returns the value, and whether it was present in the response and a valid number
+func (n Number) Float64() (float64, bool) {
+	return n.value, n.valid
+}
+
+// Present reports whether the field was in the response, whether or not it was a valid number
+func (n Number) Present() bool {
+	return n.present
+}
+
+// Valid reports whether the field was in the response and was a valid number
+func (n Number) Valid() bool {
+	return n.valid
+}
+
+// String returns the value as it was in the response, or an empty string if it was missing
+func (n Number) String() string {
+	return n.raw
+}
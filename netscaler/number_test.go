@@ -0,0 +1,82 @@
+package netscaler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		value   float64
+		present bool
+		valid   bool
+		raw     string
+	}{
+		{"quoted", `{"n":"1234"}`, 1234, true, true, "1234"},
+		{"quoted 64 bit counter", `{"n":"18446744073709551615"}`, 18446744073709551615, true, true, "18446744073709551615"},
+		{"quoted decimal", `{"n":"12.5"}`, 12.5, true, true, "12.5"},
+		{"bare integer", `{"n":42}`, 42, true, true, "42"},
+		{"bare decimal", `{"n":0.25}`, 0.25, true, true, "0.25"},
+		{"bare negative", `{"n":-3}`, -3, true, true, "-3"},
+		{"zero", `{"n":"0"}`, 0, true, true, "0"},
+		{"null", `{"n":null}`, 0, false, false, ""},
+		{"missing", `{}`, 0, false, false, ""},
+		{"empty string", `{"n":""}`, 0, true, false, ""},
+		{"invalid string", `{"n":"UP"}`, 0, true, false, "UP"},
+		{"boolean", `{"n":true}`, 0, true, false, "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				N Number `json:"n"`
+			}
+
+			err := json.Unmarshal([]byte(tt.json), &v)
+			if err != nil {
+				t.Fatalf("decoding returned %v, want no error", err)
+			}
+
+			value, ok := v.N.Float64()
+			if value != tt.value || ok != tt.valid {
+				t.Errorf("Float64() = %v, %t, want %v, %t", value, ok, tt.value, tt.valid)
+			}
+
+			if v.N.Present() != tt.present {
+				t.Errorf("Present() = %t, want %t", v.N.Present(), tt.present)
+			}
+
+			if v.N.Valid() != tt.valid {
+				t.Errorf("Valid() = %t, want %t", v.N.Valid(), tt.valid)
+			}
+
+			if v.N.String() != tt.raw {
+				t.Errorf("String() = %q, want %q", v.N.String(), tt.raw)
+			}
+		})
+	}
+}
+
+// An invalid field must not stop the rest of the resource from decoding
+func TestNumberInvalidFieldKeepsResource(t *testing.T) {
+	var s ServiceStats
+
+	err := json.Unmarshal([]byte(`{"name":"web","throughput":"not a number","totalrequests":"7"}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "web" {
+		t.Errorf("Name = %q, want web", s.Name)
+	}
+
+	if v, ok := s.TotalRequests.Float64(); v != 7 || !ok {
+		t.Errorf("TotalRequests = %v, %t, want 7, true", v, ok)
+	}
+
+	if s.Throughput.Valid() || !s.Throughput.Present() {
+		t.Errorf("Throughput should be present but invalid")
+	}
+}
@@ -0,0 +1,126 @@
+package netscaler
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithPageSize makes the client retrieve configuration collections in pages of the given number of resources
+func WithPageSize(n int) ClientOption {
+	return func(c *NitroClient) {
+		c.pageSize = n
+	}
+}
+
+// GetConfigCount sends a request to the Nitro API for the number of resources of the given type which match the query.
+func (c *NitroClient) GetConfigCount(configType string, query *Query) (int, error) {
+	return c.GetConfigCountContext(context.Background(), configType, query)
+}
+
+// GetConfigCountContext is like GetConfigCount, but the request is cancelled when the context is done.
+func (c *NitroClient) GetConfigCountContext(ctx context.Context, configType string, query *Query) (int, error) {
+	body, err := c.get(ctx, "config/"+configType, query.clone().Count().Encode())
+	if err != nil {
+		return 0, err
+	}
+
+	var response map[string]json.RawMessage
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return 0, errors.Wrap(err, "error unmarshalling response body")
+	}
+
+	// The count is returned as the only element of the collection
+	var count []struct {
+		Count int `json:"__count"`
+	}
+
+	if elements, ok := response[collectionName(configType)]; ok {
+		err = json.Unmarshal(elements, &count)
+		if err != nil {
+			return 0, errors.Wrap(err, "error unmarshalling count")
+		}
+	}
+
+	if len(count) == 0 {
+		return 0, nil
+	}
+
+	return count[0].Count, nil
+}
+
+// paged reports whether a request for configuration should be paged
+func (c *NitroClient) paged(configType string, query *Query) bool {
+	if c.pageSize <= 0 || strings.Contains(configType, "/") {
+		return false
+	}
+
+	return query == nil || (query.pageSize == 0 && !query.count && len(query.args) == 0)
+}
+
+// getConfigPages retrieves a collection one page at a time, and returns the resources from every page as one response
+func (c *NitroClient) getConfigPages(ctx context.Context, configType string, query *Query) ([]byte, error) {
+	count, err := c.GetConfigCountContext(ctx, configType, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "error counting "+configType)
+	}
+
+	if count <= c.pageSize {
+		return c.get(ctx, "config/"+configType, query.Encode())
+	}
+
+	name := collectionName(configType)
+
+	var (
+		response  map[string]json.RawMessage
+		resources []json.RawMessage
+	)
+
+	for page := 1; (page-1)*c.pageSize < count; page++ {
+		body, err := c.get(ctx, "config/"+configType, query.clone().Page(c.pageSize, page).Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		response = nil
+
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, errors.Wrap(err, "error unmarshalling response body")
+		}
+
+		var elements []json.RawMessage
+
+		if raw, ok := response[name]; ok {
+			err = json.Unmarshal(raw, &elements)
+			if err != nil {
+				return nil, errors.Wrap(err, "error unmarshalling "+name)
+			}
+		}
+
+		resources = append(resources, elements...)
+
+		// The collection has shrunk since it was counted, or the NetScaler has ignored the page size
+		if len(elements) != c.pageSize {
+			break
+		}
+	}
+
+	merged, err := json.Marshal(resources)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling "+name)
+	}
+
+	response[name] = merged
+
+	return json.Marshal(response)
+}
+
+// collectionName returns the name under which the resources of a configuration type are returned
+func collectionName(configType string) string {
+	return strings.SplitN(configType, "/", 2)[0]
+}
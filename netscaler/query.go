@@ -0,0 +1,161 @@
+package netscaler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query builds the query string of a Nitro API request
+type Query struct {
+	args         []string
+	filters      []string
+	attrs        []string
+	pageSize     int
+	pageNo       int
+	count        bool
+	statBindings bool
+	bulkBindings bool
+}
+
+// NewQuery returns an empty query
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Arg adds an argument identifying the resource to retrieve, such as the name of a service group and one of its members
+func (q *Query) Arg(name string, value string) *Query {
+	q.args = append(q.args, escape(name)+":"+escape(value))
+	return q
+}
+
+// Filter restricts the response to resources whose property is equal to the value
+func (q *Query) Filter(name string, value string) *Query {
+	q.filters = append(q.filters, escape(name)+":"+escape(value))
+	return q
+}
+
+// FilterRegex restricts the response to resources whose property matches the regular expression
+func (q *Query) FilterRegex(name string, pattern string) *Query {
+	q.filters = append(q.filters, escape(name)+":/"+escape(pattern)+"/")
+	return q
+}
+
+// Attrs restricts the properties returned for each resource
+func (q *Query) Attrs(names ...string) *Query {
+	q.attrs = append(q.attrs, names...)
+	return q
+}
+
+// Page requests one page of the resources, numbered from 1, of the given size
+func (q *Query) Page(size int, number int) *Query {
+	q.pageSize = size
+	q.pageNo = number
+	return q
+}
+
+// Count requests the number of resources rather than the resources themselves
+func (q *Query) Count() *Query {
+	q.count = true
+	return q
+}
+
+// StatBindings requests the stats of the resources bound to each resource, such as the members of a service group
+func (q *Query) StatBindings() *Query {
+	q.statBindings = true
+	return q
+}
+
+// BulkBindings requests the bindings of every resource, rather than of one named resource
+func (q *Query) BulkBindings() *Query {
+	q.bulkBindings = true
+	return q
+}
+
+// clone returns a copy of the query which can be changed without affecting the original
+func (q *Query) clone() *Query {
+	if q == nil {
+		return NewQuery()
+	}
+
+	c := *q
+	c.args = append([]string(nil), q.args...)
+	c.filters = append([]string(nil), q.filters...)
+	c.attrs = append([]string(nil), q.attrs...)
+
+	return &c
+}
+
+// Encode returns the escaped query string, without the leading "?"
+func (q *Query) Encode() string {
+	if q == nil {
+		return ""
+	}
+
+	var params []string
+
+	if len(q.args) > 0 {
+		params = append(params, "args="+strings.Join(q.args, ","))
+	}
+
+	if len(q.filters) > 0 {
+		params = append(params, "filter="+strings.Join(q.filters, ","))
+	}
+
+	if len(q.attrs) > 0 {
+		attrs := make([]string, len(q.attrs))
+		for i, a := range q.attrs {
+			attrs[i] = escape(a)
+		}
+
+		params = append(params, "attrs="+strings.Join(attrs, ","))
+	}
+
+	if q.pageSize > 0 {
+		params = append(params, "pagesize="+strconv.Itoa(q.pageSize), "pageno="+strconv.Itoa(q.pageNo))
+	}
+
+	if q.count {
+		params = append(params, "count=yes")
+	}
+
+	if q.statBindings {
+		params = append(params, "statbindings=yes")
+	}
+
+	if q.bulkBindings {
+		params = append(params, "bulkbindings=yes")
+	}
+
+	return strings.Join(params, "&")
+}
+
+// String implements fmt.Stringer, returning the encoded query
+func (q *Query) String() string {
+	return q.Encode()
+}
+
+// escape percent-encodes everything except the unreserved characters of RFC 3986
+func escape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' ||
+		'A' <= c && c <= 'Z' ||
+		'0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '~'
+}
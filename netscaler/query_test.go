@@ -0,0 +1,64 @@
+package netscaler
+
+import "testing"
+
+func TestQueryEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{"nil", nil, ""},
+		{"empty", NewQuery(), ""},
+		{"arg", NewQuery().Arg("servicegroupname", "web"), "args=servicegroupname:web"},
+		{"arg with spaces", NewQuery().Arg("servicegroupname", "my group"), "args=servicegroupname:my%20group"},
+		{"arg with separators", NewQuery().Arg("servicegroupname", "a,b:c&d=e/f"), "args=servicegroupname:a%2Cb%3Ac%26d%3De%2Ff"},
+		{"several args", NewQuery().Arg("servicegroupname", "web").Arg("servername", "10.0.0.1").Arg("port", "80"), "args=servicegroupname:web,servername:10.0.0.1,port:80"},
+		{"unreserved characters", NewQuery().Arg("name", "a-b_c.d~e"), "args=name:a-b_c.d~e"},
+		{"non-ASCII", NewQuery().Arg("name", "café"), "args=name:caf%C3%A9"},
+		{"filter", NewQuery().Filter("state", "UP"), "filter=state:UP"},
+		{"filter value with percent and plus", NewQuery().Filter("name", "50%+1"), "filter=name:50%25%2B1"},
+		{"filter regex", NewQuery().FilterRegex("name", "^web-[0-9]+$"), "filter=name:/%5Eweb-%5B0-9%5D%2B%24/"},
+		{"filter regex with alternation", NewQuery().FilterRegex("name", "a|b (c)"), "filter=name:/a%7Cb%20%28c%29/"},
+		{"several filters", NewQuery().Filter("state", "UP").FilterRegex("name", "web"), "filter=state:UP,name:/web/"},
+		{"attrs", NewQuery().Attrs("state", "port"), "attrs=state,port"},
+		{"attr with separator", NewQuery().Attrs("a,b"), "attrs=a%2Cb"},
+		{"page", NewQuery().Page(100, 3), "pagesize=100&pageno=3"},
+		{"count", NewQuery().Count(), "count=yes"},
+		{"bindings", NewQuery().StatBindings().BulkBindings(), "statbindings=yes&bulkbindings=yes"},
+		{
+			"everything",
+			NewQuery().Arg("servicegroupname", "web").Filter("state", "UP").Attrs("port").Page(10, 1).Count().StatBindings().BulkBindings(),
+			"args=servicegroupname:web&filter=state:UP&attrs=port&pagesize=10&pageno=1&count=yes&statbindings=yes&bulkbindings=yes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.Encode(); got != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+
+			if got := tt.query.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryClone(t *testing.T) {
+	q := NewQuery().Arg("servicegroupname", "web")
+	c := q.clone().Arg("servername", "10.0.0.1").Page(10, 2)
+
+	if got, want := q.Encode(), "args=servicegroupname:web"; got != want {
+		t.Errorf("original changed by its clone: got %q, want %q", got, want)
+	}
+
+	if got, want := c.Encode(), "args=servicegroupname:web,servername:10.0.0.1&pagesize=10&pageno=2"; got != want {
+		t.Errorf("clone: got %q, want %q", got, want)
+	}
+
+	if got := (*Query)(nil).clone().Encode(); got != "" {
+		t.Errorf("clone of nil query: got %q, want empty", got)
+	}
+}
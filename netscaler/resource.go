@@ -0,0 +1,187 @@
+package netscaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ResourceType is the part of the Nitro API a resource is retrieved from
+type ResourceType int
+
+// The Nitro API serves the configuration and the stats of resources separately
+const (
+	Stat ResourceType = iota
+	Config
+)
+
+func (t ResourceType) String() string {
+	if t == Config {
+		return "config"
+	}
+
+	return "stat"
+}
+
+// Resource describes a kind of resource which can be retrieved from the Nitro API
+type Resource struct {
+	// Type is whether the resource is retrieved from the stat or config API
+	Type ResourceType
+
+	// Name is the Nitro name of the resource, used as the endpoint, such as lbvserver
+	Name string
+
+	// Key is the field of the response holding the resources, if it isn't the name.  It is matched regardless of case.
+	Key string
+
+	// Element is the zero value of the struct each resource is decoded into
+	Element interface{}
+
+	// Single is set if the response holds a single object rather than an array, such as ns stats
+	Single bool
+}
+
+var (
+	resourcesMu sync.RWMutex
+	resources   = map[string]Resource{}
+)
+
+// Register adds a resource descriptor to the registry and returns it
+func Register(r Resource) Resource {
+	if r.Name == "" || r.Element == nil {
+		panic("netscaler: resource must have a name and an element type")
+	}
+
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+
+	id := r.Type.String() + "/" + r.Name
+
+	if _, ok := resources[id]; ok {
+		panic(fmt.Sprintf("netscaler: resource %s is already registered", id))
+	}
+
+	resources[id] = r
+
+	return r
+}
+
+// LookupResource returns the registered descriptor of the named resource
+func LookupResource(t ResourceType, name string) (Resource, bool) {
+	resourcesMu.RLock()
+	defer resourcesMu.RUnlock()
+
+	r, ok := resources[t.String()+"/"+name]
+
+	return r, ok
+}
+
+// path returns the Nitro path of the resource, or of one named instance of it
+func (r Resource) path(name string) string {
+	if name == "" {
+		return r.Name
+	}
+
+	return r.Name + "/" + escape(name)
+}
+
+// key returns the field of the response holding the resources
+func (r Resource) key() string {
+	if r.Key != "" {
+		return r.Key
+	}
+
+	return r.Name
+}
+
+// Fetch retrieves resources from the Nitro API, or a single named instance if name is given
+func (c *NitroClient) Fetch(r Resource, name string, query *Query) (interface{}, error) {
+	return c.FetchContext(context.Background(), r, name, query)
+}
+
+// FetchContext is like Fetch, but the request is cancelled when the context is done.
+func (c *NitroClient) FetchContext(ctx context.Context, r Resource, name string, query *Query) (interface{}, error) {
+	var (
+		body []byte
+		err  error
+	)
+
+	switch {
+	case r.Type == Stat:
+		body, err = c.GetStatsContext(ctx, r.path(name), query)
+	case r.Single:
+		// A single resource isn't a collection, so is never paged
+		body, err = c.get(ctx, "config/"+r.path(name), query.Encode())
+	default:
+		body, err = c.GetConfigContext(ctx, r.path(name), query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]json.RawMessage
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling response body")
+	}
+
+	elemType := reflect.TypeOf(r.Element)
+
+	result := reflect.New(elemType)
+	if !r.Single {
+		result = reflect.New(reflect.SliceOf(elemType))
+	}
+
+	for key, raw := range response {
+		if !strings.EqualFold(key, r.key()) {
+			continue
+		}
+
+		err = json.Unmarshal(raw, result.Interface())
+		if err != nil {
+			return nil, errors.Wrap(err, "error unmarshalling "+r.Name)
+		}
+
+		break
+	}
+
+	return result.Elem().Interface(), nil
+}
+
+// Stream retrieves resources from the Nitro API like Fetch, but calls each for every resource as it is read
+func (c *NitroClient) Stream(r Resource, name string, query *Query, each func(interface{}) error) error {
+	return c.StreamContext(context.Background(), r, name, query, each)
+}
+
+// StreamContext is like Stream, but the request is cancelled when the context is done.
+func (c *NitroClient) StreamContext(ctx context.Context, r Resource, name string, query *Query, each func(interface{}) error) error {
+	if r.Single {
+		return errors.New("netscaler: " + r.Name + " is a single resource and can't be streamed")
+	}
+
+	body, err := c.open(ctx, r.Type.String()+"/"+r.path(name), query.Encode())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	elemType := reflect.TypeOf(r.Element)
+
+	return streamArray(body, r.key(), func(dec *json.Decoder) error {
+		elem := reflect.New(elemType)
+
+		err := dec.Decode(elem.Interface())
+		if err != nil {
+			return errors.Wrap(err, "error unmarshalling "+r.Name)
+		}
+
+		return each(elem.Elem().Interface())
+	})
+}
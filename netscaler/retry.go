@@ -0,0 +1,109 @@
+package netscaler
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxRetryBackoff caps the time waited before any one retry
+const maxRetryBackoff = 10 * time.Second
+
+// WithRetries makes the client retry GET requests and logins which fail with a transient error up to n times
+func WithRetries(n int, backoff time.Duration) ClientOption {
+	return func(c *NitroClient) {
+		c.maxRetries = n
+		c.retryBackoff = backoff
+	}
+}
+
+// WithRetryHook sets a function which is called with the error of a failed attempt each time a request or login is retried
+func WithRetryHook(f func(err error)) ClientOption {
+	return func(c *NitroClient) {
+		c.onRetry = f
+	}
+}
+
+// retry calls attempt until it succeeds, fails with an error which isn't transient, or the retries of the client are used up, and returns the last error
+func (c *NitroClient) retry(ctx context.Context, attempt func() error) error {
+	for n := 0; ; n++ {
+		err := attempt()
+		if err == nil || n >= c.maxRetries || !isTransient(ctx, err) {
+			return err
+		}
+
+		wait := c.backoff(n)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return err
+		}
+
+		if c.onRetry != nil {
+			c.onRetry(err)
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff returns the jittered time to wait before the given retry, numbered from 0
+func (c *NitroClient) backoff(n int) time.Duration {
+	if c.retryBackoff <= 0 {
+		return 0
+	}
+
+	d := c.retryBackoff << uint(n)
+	if d <= 0 || d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+
+	half := int64(d / 2)
+
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// isTransient reports whether a failed attempt is worth retrying
+func isTransient(ctx context.Context, err error) bool {
+	// The request was abandoned rather than failing
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var nitroErr *NitroError
+
+	if errors.As(err, &nitroErr) {
+		switch nitroErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Connections which are refused or reset
+	var opErr *net.OpError
+
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,42 +1,27 @@
 package netscaler
 
-import (
-	"encoding/json"
-
-	"github.com/pkg/errors"
-)
-
 // NSStats represents the data returned from the /stat/ns Nitro API endpoint
 type NSStats struct {
-	CPUUsagePcnt                           float64 `json:"cpuusagepcnt"`
-	MemUsagePcnt                           float64 `json:"memusagepcnt"`
-	MgmtCPUUsagePcnt                       float64 `json:"mgmtcpuusagepcnt"`
-	PktCPUUsagePcnt                        float64 `json:"pktcpuusagepcnt"`
-	FlashPartitionUsage                    float64 `json:"disk0perusage"`
-	VarPartitionUsage                      float64 `json:"disk1perusage"`
-	ReceivedMbPerSecond                    float64 `json:"rxmbitsrate"`
-	TransmitMbPerSecond                    float64 `json:"txmbitsrate"`
-	HTTPRequestsRate                       float64 `json:"httprequestsrate"`
-	HTTPResponsesRate                      float64 `json:"httpresponsesrate"`
-	TCPCurrentClientConnections            string  `json:"tcpcurclientconn"`
-	TCPCurrentClientConnectionsEstablished string  `json:"tcpcurclientconnestablished"`
-	TCPCurrentServerConnections            string  `json:"tcpcurserverconn"`
-	TCPCurrentServerConnectionsEstablished string  `json:"tcpcurserverconnestablished"`
+	CPUUsagePcnt                           Number `json:"cpuusagepcnt"`
+	MemUsagePcnt                           Number `json:"memusagepcnt"`
+	MgmtCPUUsagePcnt                       Number `json:"mgmtcpuusagepcnt"`
+	PktCPUUsagePcnt                        Number `json:"pktcpuusagepcnt"`
+	FlashPartitionUsage                    Number `json:"disk0perusage"`
+	VarPartitionUsage                      Number `json:"disk1perusage"`
+	ReceivedMbPerSecond                    Number `json:"rxmbitsrate"`
+	TransmitMbPerSecond                    Number `json:"txmbitsrate"`
+	HTTPRequestsRate                       Number `json:"httprequestsrate"`
+	HTTPResponsesRate                      Number `json:"httpresponsesrate"`
+	TCPCurrentClientConnections            Number `json:"tcpcurclientconn"`
+	TCPCurrentClientConnectionsEstablished Number `json:"tcpcurclientconnestablished"`
+	TCPCurrentServerConnections            Number `json:"tcpcurserverconn"`
+	TCPCurrentServerConnectionsEstablished Number `json:"tcpcurserverconnestablished"`
 }
 
-// GetNSStats queries the Nitro API for ns stats
-func GetNSStats(c *NitroClient, querystring string) (NSAPIResponse, error) {
-	stats, err := c.GetStats("ns", querystring)
-	if err != nil {
-		return NSAPIResponse{}, err
-	}
-
-	var response = new(NSAPIResponse)
-
-	err = json.Unmarshal(stats, &response)
-	if err != nil {
-		return NSAPIResponse{}, errors.Wrap(err, "error unmarshalling response body")
-	}
-
-	return *response, nil
-}
+// NSStatsResource describes the stats of the NetScaler as a whole
+var NSStatsResource = Register(Resource{
+	Type:    Stat,
+	Name:    "ns",
+	Element: NSStats{},
+	Single:  true,
+})
@@ -1,44 +1,30 @@
 package netscaler
 
-import (
-	"encoding/json"
-
-	"github.com/pkg/errors"
-)
-
-// ServiceGroupMemberStats represents the data returned from the /stat/servicegroupmember Nitro API endpoint
+// ServiceGroupMemberStats represents the data returned from the /stat/servicegroupmember Nitro API endpoint, or for each member by /stat/servicegroup with statbindings
 type ServiceGroupMemberStats struct {
-	State                        string  `json:"state"`
-	AvgTimeToFirstByte           string  `json:"avgsvrttfb"`
-	TotalRequests                string  `json:"totalrequests"`
-	RequestsRate                 float64 `json:"requestsrate"`
-	TotalResponses               string  `json:"totalresponses"`
-	ResponsesRate                float64 `json:"responsesrate"`
-	TotalRequestBytes            string  `json:"totalrequestbytes"`
-	RequestBytesRate             float64 `json:"requestbytesrate"`
-	TotalResponseBytes           string  `json:"totalresponsebytes"`
-	ResponseBytesRate            float64 `json:"responsebytesrate"`
-	CurrentClientConnections     string  `json:"curclntconnections"`
-	SurgeCount                   string  `json:"surgecount"`
-	CurrentServerConnections     string  `json:"cursrvrconnections"`
-	ServerEstablishedConnections string  `json:"svrestablishedconn"`
-	CurrentReusePool             string  `json:"curreusepool"`
-	MaxClients                   string  `json:"maxclients"`
+	PrimaryIPAddress             string `json:"primaryipaddress"`
+	PrimaryPort                  int64  `json:"primaryport"`
+	State                        string `json:"state"`
+	AvgTimeToFirstByte           Number `json:"avgsvrttfb"`
+	TotalRequests                Number `json:"totalrequests"`
+	RequestsRate                 Number `json:"requestsrate"`
+	TotalResponses               Number `json:"totalresponses"`
+	ResponsesRate                Number `json:"responsesrate"`
+	TotalRequestBytes            Number `json:"totalrequestbytes"`
+	RequestBytesRate             Number `json:"requestbytesrate"`
+	TotalResponseBytes           Number `json:"totalresponsebytes"`
+	ResponseBytesRate            Number `json:"responsebytesrate"`
+	CurrentClientConnections     Number `json:"curclntconnections"`
+	SurgeCount                   Number `json:"surgecount"`
+	CurrentServerConnections     Number `json:"cursrvrconnections"`
+	ServerEstablishedConnections Number `json:"svrestablishedconn"`
+	CurrentReusePool             Number `json:"curreusepool"`
+	MaxClients                   Number `json:"maxclients"`
 }
 
-// GetServiceGroupMemberStats queries the Nitro API for servicegroup member stats
-func GetServiceGroupMemberStats(c *NitroClient, querystring string) (NSAPIResponse, error) {
-	stats, err := c.GetStats("servicegroupmember", querystring)
-	if err != nil {
-		return NSAPIResponse{}, err
-	}
-
-	var response = new(NSAPIResponse)
-
-	err = json.Unmarshal(stats, &response)
-	if err != nil {
-		return NSAPIResponse{}, errors.Wrap(err, "error unmarshalling response body")
-	}
-
-	return *response, nil
-}
+// ServiceGroupMemberStatsResource describes the stats of a service group member, identified with Arg
+var ServiceGroupMemberStatsResource = Register(Resource{
+	Type:    Stat,
+	Name:    "servicegroupmember",
+	Element: ServiceGroupMemberStats{},
+})
@@ -0,0 +1,8 @@
+package netscaler
+
+// ServiceGroupStatsResource describes the stats of each service group
+var ServiceGroupStatsResource = Register(Resource{
+	Type:    Stat,
+	Name:    "servicegroup",
+	Element: ServiceGroups{},
+})
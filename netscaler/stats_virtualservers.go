@@ -1,45 +1,29 @@
 package netscaler
 
-import (
-	"encoding/json"
-
-	"github.com/pkg/errors"
-)
-
 // VirtualServerStats represents the data returned from the /stat/lbvserver Nitro API endpoint
 type VirtualServerStats struct {
-	Name                     string  `json:"name"`
-	WaitingRequests          string  `json:"vsvrsurgecount"`
-	Health                   string  `json:"vslbhealth"`
-	InactiveServices         string  `json:"inactsvcs"`
-	ActiveServices           string  `json:"actsvcs"`
-	TotalHits                string  `json:"tothits"`
-	HitsRate                 float64 `json:"hitsrate"`
-	TotalRequests            string  `json:"totalrequests"`
-	RequestsRate             float64 `json:"requestsrate"`
-	TotalResponses           string  `json:"totalresponses"`
-	ResponsesRate            float64 `json:"responsesrate"`
-	TotalRequestBytes        string  `json:"totalrequestsbytes"`
-	RequestBytesRate         float64 `json:"requestbytesrate"`
-	TotalResponseBytes       string  `json:"totalresponsebytes"`
-	ResponseBytesRate        float64 `json:"responsebytesrate"`
-	CurrentClientConnections string  `json:"curclntconnections"`
-	CurrentServerConnections string  `json:"cursrvrconnections"`
+	Name                     string `json:"name"`
+	WaitingRequests          Number `json:"vsvrsurgecount"`
+	Health                   Number `json:"vslbhealth"`
+	InactiveServices         Number `json:"inactsvcs"`
+	ActiveServices           Number `json:"actsvcs"`
+	TotalHits                Number `json:"tothits"`
+	HitsRate                 Number `json:"hitsrate"`
+	TotalRequests            Number `json:"totalrequests"`
+	RequestsRate             Number `json:"requestsrate"`
+	TotalResponses           Number `json:"totalresponses"`
+	ResponsesRate            Number `json:"responsesrate"`
+	TotalRequestBytes        Number `json:"totalrequestsbytes"`
+	RequestBytesRate         Number `json:"requestbytesrate"`
+	TotalResponseBytes       Number `json:"totalresponsebytes"`
+	ResponseBytesRate        Number `json:"responsebytesrate"`
+	CurrentClientConnections Number `json:"curclntconnections"`
+	CurrentServerConnections Number `json:"cursrvrconnections"`
 }
 
-// GetVirtualServerStats queries the Nitro API for virtual server stats
-func GetVirtualServerStats(c *NitroClient, querystring string) (NSAPIResponse, error) {
-	stats, err := c.GetStats("lbvserver", querystring)
-	if err != nil {
-		return NSAPIResponse{}, err
-	}
-
-	var response = new(NSAPIResponse)
-
-	err = json.Unmarshal(stats, &response)
-	if err != nil {
-		return NSAPIResponse{}, errors.Wrap(err, "error unmarshalling response body")
-	}
-
-	return *response, nil
-}
+// VirtualServerStatsResource describes the stats of each load balancing virtual server
+var VirtualServerStatsResource = Register(Resource{
+	Type:    Stat,
+	Name:    "lbvserver",
+	Element: VirtualServerStats{},
+})
@@ -0,0 +1,72 @@
+package netscaler
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// streamArray decodes a Nitro response, calling each for every element of the array with the given name
+func streamArray(r io.Reader, name string, each func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return err
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return errors.Wrap(err, "error reading response body")
+		}
+
+		key, _ := t.(string)
+
+		if !strings.EqualFold(key, name) {
+			var skip json.RawMessage
+
+			err = dec.Decode(&skip)
+			if err != nil {
+				return errors.Wrap(err, "error reading response body")
+			}
+
+			continue
+		}
+
+		err = expectDelim(dec, '[')
+		if err != nil {
+			return err
+		}
+
+		for dec.More() {
+			err = each(dec)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = expectDelim(dec, ']')
+		if err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// expectDelim reads the next token, which must be the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, "error reading response body")
+	}
+
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return errors.Errorf("error reading response body: expected %s but found %v", delim, t)
+	}
+
+	return nil
+}
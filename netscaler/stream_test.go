@@ -0,0 +1,160 @@
+package netscaler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// serviceStatsBody returns a stat/service response holding n services
+func serviceStatsBody(n int) string {
+	var b bytes.Buffer
+
+	b.WriteString(`{"errorcode":0,"message":"Done","service":[`)
+
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		fmt.Fprintf(&b, `{"name":"svc-%d","throughput":"%d","throughputrate":%d,"avgsvrttfb":"12","state":"UP","totalrequests":"%d","requestsrate":3,"totalresponses":"%d","responsesrate":3,"totalrequestbytes":"123456789","requestbytesrate":1024,"totalresponsebytes":"987654321","responsebytesrate":2048,"curclntconnections":"4","surgecount":"0","cursrvrconnections":"5","svrestablishedconn":"5","curreusepool":"1","maxclients":"0","curload":"0","vsvrservicehits":"%d","vsvrservicehitsrate":1,"activetransactions":"0"}`, i, i, i, i, i, i)
+	}
+
+	b.WriteString(`]}`)
+
+	return b.String()
+}
+
+// newBenchmarkClient returns a client logged in to the fake NetScaler
+func newBenchmarkClient(b *testing.B, ns *fakeNitro) *NitroClient {
+	c, err := NewNitroClient(ns.URL, "stats", "secret")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	err = Connect(c)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return c
+}
+
+func BenchmarkFetchServices(b *testing.B) {
+	ns := newFakeNitro("secret", serviceStatsBody(10000))
+	defer ns.Close()
+
+	c := newBenchmarkClient(b, ns)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		result, err := c.FetchContext(context.Background(), ServiceStatsResource, "", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if n := len(result.([]ServiceStats)); n != 10000 {
+			b.Fatalf("got %d services, want 10000", n)
+		}
+	}
+}
+
+func BenchmarkStreamServices(b *testing.B) {
+	ns := newFakeNitro("secret", serviceStatsBody(10000))
+	defer ns.Close()
+
+	c := newBenchmarkClient(b, ns)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		n := 0
+
+		err := c.StreamContext(context.Background(), ServiceStatsResource, "", nil, func(interface{}) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if n != 10000 {
+			b.Fatalf("got %d services, want 10000", n)
+		}
+	}
+}
+
+func TestStreamServices(t *testing.T) {
+	ns := newFakeNitro("secret", serviceStatsBody(3))
+	defer ns.Close()
+
+	c, err := NewNitroClient(ns.URL, "stats", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	err = c.Stream(ServiceStatsResource, "", nil, func(resource interface{}) error {
+		names = append(names, resource.(ServiceStats).Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[svc-0 svc-1 svc-2]" {
+		t.Errorf("streamed %v, want [svc-0 svc-1 svc-2]", names)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	body := serviceStatsBody(10)
+	size := int64(len(body))
+
+	tests := []struct {
+		name    string
+		limit   int64
+		tooLong bool
+	}{
+		{"response of exactly the limit", size, false},
+		{"response one byte over the limit", size - 1, true},
+		{"no limit", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := newFakeNitro("secret", body)
+			defer ns.Close()
+
+			c, err := NewNitroClient(ns.URL, "stats", "secret", WithMaxResponseSize(tt.limit))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.GetStats("service", nil)
+
+			if tt.tooLong {
+				if !errors.Is(err, ErrResponseTooLarge) {
+					t.Errorf("GetStats returned %v, want ErrResponseTooLarge", err)
+				}
+			} else if err != nil || string(got) != body {
+				t.Errorf("GetStats returned %d bytes and %v, want the whole response", len(got), err)
+			}
+
+			err = c.Stream(ServiceStatsResource, "", nil, func(interface{}) error {
+				return nil
+			})
+
+			if tt.tooLong != errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("Stream returned %v, want ErrResponseTooLarge: %t", err, tt.tooLong)
+			}
+		})
+	}
+}
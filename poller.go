@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+var lastSuccessfulPoll = prometheus.NewDesc(
+	"netscaler_last_successful_poll_timestamp_seconds",
+	"Time of the last poll of the NetScaler in which at least one collector succeeded; 0 if there has been none.",
+	[]string{
+		"ns_instance",
+	},
+	nil,
+)
+
+var (
+	// pollers holds the poller of each target which is polled in the background, by URL
+	pollers   = map[string]*poller{}
+	pollersMu sync.RWMutex
+)
+
+// poller scrapes a target on its own interval and keeps the result until it is older than maxAge, so that scrapes of /metrics never reach the NetScaler.
+type poller struct {
+	target   target
+	interval time.Duration
+	maxAge   time.Duration
+	status   prometheus.Gatherer
+	done     chan struct{}
+
+	mu          sync.RWMutex
+	families    []*dto.MetricFamily
+	polledAt    time.Time
+	lastSuccess time.Time
+}
+
+// newPoller creates the poller for a target, serving results for three poll intervals if maxAge is zero.
+func newPoller(t target, interval time.Duration, maxAge time.Duration) *poller {
+	if maxAge == 0 {
+		maxAge = 3 * interval
+	}
+
+	p := &poller{
+		target:   t,
+		interval: interval,
+		maxAge:   maxAge,
+		done:     make(chan struct{}),
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(p)
+
+	p.status = labelGatherer{gatherer: registry, labels: t.Labels}
+
+	return p
+}
+
+// run polls the target immediately and then every interval, without overlapping polls, until the poller is stopped or the exporter exits.
+func (p *poller) run() {
+	p.poll()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// poll scrapes the target with every enabled collector and keeps the result
+func (p *poller) poll() {
+	collectors, err := enabledCollectors(p.target.module(), nil)
+	if err != nil {
+		level.Error(logger).Log("msg", err, "target", p.target.URL)
+		return
+	}
+
+	// A poll must finish before the next is due
+	timeout := p.interval
+	if *pollTimeout > 0 && *pollTimeout < timeout {
+		timeout = *pollTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	gatherer, err := targetGatherer(ctx, p.target, collectors)
+	if err != nil {
+		level.Error(logger).Log("msg", err, "target", p.target.URL)
+		return
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		level.Error(logger).Log("msg", err, "target", p.target.URL)
+		return
+	}
+
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.families = families
+	p.polledAt = now
+
+	if pollSucceeded(families) {
+		p.lastSuccess = now
+	}
+}
+
+// pollSucceeded reports whether netscaler_up was 1 in the result of a poll
+func pollSucceeded(families []*dto.MetricFamily) bool {
+	for _, mf := range families {
+		if mf.GetName() != "netscaler_up" {
+			continue
+		}
+
+		for _, m := range mf.Metric {
+			if m.GetGauge().GetValue() == 1 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Gather implements prometheus.Gatherer, returning the result of the last poll if it is recent enough
+func (p *poller) Gather() ([]*dto.MetricFamily, error) {
+	p.mu.RLock()
+	var families []*dto.MetricFamily
+	if !p.polledAt.IsZero() && time.Since(p.polledAt) <= p.maxAge {
+		families = append(families, p.families...)
+	}
+	p.mu.RUnlock()
+
+	status, err := p.status.Gather()
+
+	return append(families, status...), err
+}
+
+// Describe implements Collector
+func (p *poller) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lastSuccessfulPoll
+}
+
+// Collect implements Collector, exporting the time of the last successful poll
+func (p *poller) Collect(ch chan<- prometheus.Metric) {
+	p.mu.RLock()
+	lastSuccess := p.lastSuccess
+	p.mu.RUnlock()
+
+	timestamp := 0.0
+	if !lastSuccess.IsZero() {
+		timestamp = float64(lastSuccess.UnixNano()) / 1e9
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		lastSuccessfulPoll, prometheus.GaugeValue, timestamp, instanceName(p.target.URL),
+	)
+}
+
+// startPollers starts polling every target which has a poll interval in the background
+func startPollers() {
+	for _, t := range cfg.exportedTargets() {
+		startPoller(t)
+	}
+}
+
+// startPoller starts polling the target in the background if it has a poll interval, replacing any poller it already has
+func startPoller(t target) {
+	interval := t.PollInterval
+	if interval == 0 {
+		interval = *pollInterval
+	}
+
+	if interval == 0 {
+		return
+	}
+
+	p := newPoller(t, interval, *pollMaxAge)
+
+	stopPoller(t.URL)
+
+	pollersMu.Lock()
+	pollers[t.URL] = p
+	pollersMu.Unlock()
+
+	level.Info(logger).Log("msg", "Polling target in the background", "target", t.URL, "interval", interval, "max_age", p.maxAge)
+
+	go p.run()
+}
+
+// stopPoller stops polling the target with the given URL, if it is polled in the background
+func stopPoller(url string) {
+	pollersMu.Lock()
+	p, ok := pollers[url]
+	delete(pollers, url)
+	pollersMu.Unlock()
+
+	if ok {
+		close(p.done)
+	}
+}
+
+// findPoller returns the poller of the target with the given URL, if it is polled in the background
+func findPoller(url string) (*poller, bool) {
+	pollersMu.RLock()
+	defer pollersMu.RUnlock()
+
+	p, ok := pollers[url]
+
+	return p, ok
+}
+
+// syncPollers starts and stops polling targets as the discovered targets and their labels change.
+func syncPollers(previous []target, current []target) {
+	before := map[string]target{}
+	for _, t := range previous {
+		before[t.URL] = t
+	}
+
+	for _, t := range current {
+		old, ok := before[t.URL]
+		delete(before, t.URL)
+
+		if ok && reflect.DeepEqual(old.Labels, t.Labels) {
+			continue
+		}
+
+		// A target which is also configured, or discovered from another ADM first, is polled as that target
+		if found, ok := cfg.findTarget(t.URL); ok && found.ADM == t.ADM && reflect.DeepEqual(found.Labels, t.Labels) {
+			startPoller(cfg.exported(t))
+		}
+	}
+
+	for url := range before {
+		if _, ok := cfg.findTarget(url); !ok {
+			stopPoller(url)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/go-kit/kit/log/level"
+)
+
+// targetURL turns the target parameter of a probe into the base URL of the NetScaler, assuming HTTPS for a bare hostname or IP address.
+func targetURL(target string) string {
+	if strings.Contains(target, "://") {
+		return target
+	}
+
+	return "https://" + target
+}
+
+// scrapeContext returns the context for a scrape, which is done when the scrape is abandoned, shortly before Prometheus gives up on it, or after scrape_timeout if Prometheus didn't send its timeout
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout, err := strconv.ParseFloat(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64)
+	if err != nil || timeout <= 0 {
+		if *scrapeTimeout <= 0 {
+			return context.WithCancel(r.Context())
+		}
+
+		return context.WithTimeout(r.Context(), *scrapeTimeout)
+	}
+
+	// Leave time to send the results before Prometheus times out
+	deadline := time.Duration(timeout*float64(time.Second)) - *scrapeTimeoutOffset
+	if deadline <= 0 {
+		deadline = time.Duration(timeout * float64(time.Second))
+	}
+
+	return context.WithTimeout(r.Context(), deadline)
+}
+
+// probeHandler scrapes the NetScaler given in the target parameter, using the credentials of the requested module.
+func probeHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	targetParam := params.Get("target")
+	if targetParam == "" {
+		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+		return
+	}
+
+	moduleName := params.Get("module")
+	if moduleName == "" {
+		moduleName = defaultModule
+
+		if t, ok := cfg.findTarget(targetParam); ok {
+			moduleName = t.Module
+		}
+	}
+
+	_, ok := cfg.Modules[moduleName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown module '%s'", moduleName), http.StatusBadRequest)
+		return
+	}
+
+	t := target{
+		URL:    targetURL(targetParam),
+		Module: moduleName,
+	}
+
+	configured, isConfigured := cfg.findTarget(targetParam)
+
+	if isConfigured {
+		t.URL = configured.URL
+		t.Labels = configured.Labels
+		t.TLS = configured.TLS
+		t.Filters = configured.Filters
+		t.AuthMode = configured.AuthMode
+		t.ADM = configured.ADM
+	}
+
+	if adm := params.Get("adm"); adm != "" {
+		// Only ADMs in the configuration can be given, so that the credentials of a module aren't sent anywhere else
+		if !cfg.knownADM(adm) {
+			http.Error(w, fmt.Sprintf("Unknown ADM '%s'", adm), http.StatusBadRequest)
+			return
+		}
+
+		t.ADM = adm
+	}
+
+	// The credentials of a module are only sent to configured targets, ADMs and the hosts its probe_targets allow
+	if !isConfigured && t.ADM == "" && !t.module().allowsProbe(t.URL) {
+		http.Error(w, fmt.Sprintf("Target '%s' is not configured, and not allowed by the probe_targets of module '%s'", targetParam, moduleName), http.StatusForbidden)
+		return
+	}
+
+	collectors, err := enabledCollectors(t.module(), params["collect[]"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+
+	gatherer, err := targetGatherer(ctx, t, collectors)
+	if err != nil {
+		level.Error(logger).Log("msg", err, "target", targetParam, "module", moduleName)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// targetGatherer returns a gatherer which scrapes the target with the given collectors, returning what it has collected when the context is done.
+func targetGatherer(ctx context.Context, t target, collectors map[string]bool) (prometheus.Gatherer, error) {
+	nsClient, err := clients.get(t)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewExporter(ctx, nsClient, t.URL, t.module(), collectors))
+
+	return labelGatherer{gatherer: registry, labels: t.Labels}, nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// envRE matches the ${NAME} references to environment variables which are expanded in credentials
+var envRE = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// expandEnv replaces each ${NAME}, but not a bare $NAME, in s with the value of the environment variable.
+func expandEnv(s string) (string, error) {
+	var err error
+
+	expanded := envRE.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envRE.FindStringSubmatch(ref)[1]
+
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return value
+	})
+
+	return expanded, err
+}
+
+// secretRef identifies a secret held by a secret provider
+type secretRef struct {
+	Provider string `yaml:"provider"`
+	Path     string `yaml:"path"`
+	Key      string `yaml:"key"`
+}
+
+// secretProvider reads secrets, such as passwords, from an external store, and must be safe for concurrent use.
+type secretProvider interface {
+	// secret returns the value of the key of the secret at path
+	secret(ctx context.Context, path string, key string) (string, error)
+}
+
+// credentials returns the username and password of a module, reading the password afresh from its file, environment variables or secret provider.
+func (cfg *config) credentials(ctx context.Context, m module) (string, string, error) {
+	username, err := expandEnv(m.Username)
+	if err != nil {
+		return "", "", errors.Wrap(err, "error reading username")
+	}
+
+	var password string
+
+	switch {
+	case m.PasswordFile != "":
+		content, err := ioutil.ReadFile(m.PasswordFile)
+		if err != nil {
+			return "", "", errors.Wrap(err, "error reading password file")
+		}
+
+		password = strings.TrimRight(string(content), "\r\n")
+	case m.PasswordSecret != nil:
+		provider, ok := cfg.providers[m.PasswordSecret.provider()]
+		if !ok {
+			return "", "", fmt.Errorf("secret provider %q is not configured", m.PasswordSecret.provider())
+		}
+
+		password, err = provider.secret(ctx, m.PasswordSecret.Path, m.PasswordSecret.Key)
+		if err != nil {
+			return "", "", errors.Wrap(err, "error reading password secret")
+		}
+	default:
+		password, err = expandEnv(m.Password)
+		if err != nil {
+			return "", "", errors.Wrap(err, "error reading password")
+		}
+	}
+
+	return username, password, nil
+}
+
+// provider returns the name of the provider of the secret, which defaults to vault
+func (s secretRef) provider() string {
+	if s.Provider == "" {
+		return "vault"
+	}
+
+	return s.Provider
+}
+
+// vaultConfig holds the settings used to read secrets from HashiCorp Vault, defaulting to the VAULT_ADDR and VAULT_TOKEN environment variables
+type vaultConfig struct {
+	Address   string    `yaml:"address"`
+	Token     string    `yaml:"token"`
+	TokenFile string    `yaml:"token_file"`
+	Namespace string    `yaml:"namespace"`
+	TLS       tlsConfig `yaml:"tls_config"`
+}
+
+// vaultProvider reads secrets from a key/value secrets engine of HashiCorp Vault; both versions 1 and 2 of the engine are supported
+type vaultProvider struct {
+	config vaultConfig
+	client *http.Client
+}
+
+// newVaultProvider creates the provider from its settings, filling in defaults from the environment
+func newVaultProvider(c vaultConfig) (*vaultProvider, error) {
+	var err error
+
+	c.Address, err = expandEnv(c.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid address")
+	}
+
+	if c.Address == "" {
+		c.Address = os.Getenv("VAULT_ADDR")
+	}
+
+	if c.Address == "" {
+		return nil, errors.New("address must be set, or the VAULT_ADDR environment variable")
+	}
+
+	if c.Token == "" && c.TokenFile == "" && os.Getenv("VAULT_TOKEN") == "" {
+		return nil, errors.New("token or token_file must be set, or the VAULT_TOKEN environment variable")
+	}
+
+	tlsClientConfig, err := c.TLS.tlsClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid tls_config")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsClientConfig
+
+	return &vaultProvider{
+		config: c,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+	}, nil
+}
+
+// token returns the Vault token, reading it from its file each time so that a renewed token is picked up
+func (v *vaultProvider) token() (string, error) {
+	if v.config.TokenFile != "" {
+		content, err := ioutil.ReadFile(v.config.TokenFile)
+		if err != nil {
+			return "", errors.Wrap(err, "error reading token file")
+		}
+
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	if v.config.Token != "" {
+		return expandEnv(v.config.Token)
+	}
+
+	return os.Getenv("VAULT_TOKEN"), nil
+}
+
+// secret implements secretProvider for a path of the HTTP API without its /v1/ prefix, such as secret/data/netscaler
+func (v *vaultProvider) secret(ctx context.Context, path string, key string) (string, error) {
+	token, err := v.token()
+	if err != nil {
+		return "", err
+	}
+
+	url := strings.TrimRight(v.config.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating HTTP request")
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "error sending request to vault")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading vault response")
+	}
+
+	var response struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+
+	err = json.Unmarshal(body, &response)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return "", errors.Wrap(err, "error unmarshalling vault response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s: %s", resp.Status, path, strings.Join(response.Errors, "; "))
+	}
+
+	data := response.Data
+
+	// Version 2 of the engine nests the secret, alongside its metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+
+	return value, nil
+}
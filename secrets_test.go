@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakeVault starts a Vault API which answers every request with the given status and body, recording the last request; the caller must Close it
+func newFakeVault(status int, body string, last **http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*last = r
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestVaultSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		body    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "version 1",
+			path:   "secret/netscaler",
+			status: http.StatusOK,
+			body:   `{"data":{"password":"v1-secret"}}`,
+			want:   "v1-secret",
+		},
+		{
+			name:   "version 2",
+			path:   "secret/data/netscaler",
+			status: http.StatusOK,
+			body:   `{"data":{"data":{"password":"v2-secret"},"metadata":{"version":3}}}`,
+			want:   "v2-secret",
+		},
+		{
+			name:   "version 1 secret with a data key",
+			path:   "secret/netscaler",
+			status: http.StatusOK,
+			body:   `{"data":{"data":{"password":"nested"},"password":"v1-secret"}}`,
+			want:   "v1-secret",
+		},
+		{
+			name:    "missing key",
+			path:    "secret/data/netscaler",
+			status:  http.StatusOK,
+			body:    `{"data":{"data":{"username":"stats"},"metadata":{}}}`,
+			wantErr: `has no key "password"`,
+		},
+		{
+			name:    "non-string value",
+			path:    "secret/netscaler",
+			status:  http.StatusOK,
+			body:    `{"data":{"password":42}}`,
+			wantErr: `has no key "password"`,
+		},
+		{
+			name:    "forbidden",
+			path:    "secret/data/netscaler",
+			status:  http.StatusForbidden,
+			body:    `{"errors":["permission denied"]}`,
+			wantErr: "403 Forbidden for secret/data/netscaler: permission denied",
+		},
+		{
+			name:    "not found",
+			path:    "secret/data/missing",
+			status:  http.StatusNotFound,
+			body:    `{"errors":[]}`,
+			wantErr: "404 Not Found for secret/data/missing",
+		},
+		{
+			name:    "error status without JSON",
+			path:    "secret/netscaler",
+			status:  http.StatusBadGateway,
+			body:    `upstream unavailable`,
+			wantErr: "502 Bad Gateway",
+		},
+		{
+			name:    "invalid JSON",
+			path:    "secret/netscaler",
+			status:  http.StatusOK,
+			body:    `{"data":`,
+			wantErr: "error unmarshalling vault response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last *http.Request
+
+			srv := newFakeVault(tt.status, tt.body, &last)
+			defer srv.Close()
+
+			v, err := newVaultProvider(vaultConfig{Address: srv.URL, Token: "s.token", Namespace: "ops"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := v.secret(context.Background(), tt.path, "password")
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			if want := "/v1/" + tt.path; last.URL.Path != want {
+				t.Errorf("requested %s, want %s", last.URL.Path, want)
+			}
+
+			if got := last.Header.Get("X-Vault-Token"); got != "s.token" {
+				t.Errorf("sent X-Vault-Token %q, want s.token", got)
+			}
+
+			if got := last.Header.Get("X-Vault-Namespace"); got != "ops" {
+				t.Errorf("sent X-Vault-Namespace %q, want ops", got)
+			}
+		})
+	}
+}
+
+func TestVaultSecretWithoutNamespace(t *testing.T) {
+	var last *http.Request
+
+	srv := newFakeVault(http.StatusOK, `{"data":{"password":"secret"}}`, &last)
+	defer srv.Close()
+
+	v, err := newVaultProvider(vaultConfig{Address: srv.URL + "/", Token: "s.token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.secret(context.Background(), "/secret/netscaler", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.URL.Path != "/v1/secret/netscaler" {
+		t.Errorf("requested %s, want /v1/secret/netscaler", last.URL.Path)
+	}
+
+	if _, ok := last.Header["X-Vault-Namespace"]; ok {
+		t.Error("sent X-Vault-Namespace without a namespace configured")
+	}
+}